@@ -0,0 +1,323 @@
+// docsite-search.js searches a search bundle written by `docsite search-bundle`, for sites that are
+// served without a docsite server (such as static exports). Results have the same shape as the
+// JSON responses of /search.json.
+//
+// Usage:
+//
+//   docsiteSearch.load('/assets/search-index.json').then(function (bundle) {
+//     var result = docsiteSearch.search(bundle, 'query', { offset: 0, limit: 10 })
+//   })
+//
+// Queries match documents containing all of their words (in any order). A word prefixed with "-"
+// excludes documents containing it, and a word ending in "*" matches words beginning with it.
+// Quoted phrases are searched as separate words, and field qualifiers (such as "title:") are
+// ignored.
+;(function (root) {
+    'use strict'
+
+    var FORMAT_VERSION = 1
+    var EXCERPT_MAX_LENGTH = 220
+    var WORD = /[\p{L}\p{Nd}\p{Mn}]+/gu
+    var MARK = /\p{Mn}/u
+
+    // load fetches the bundle at the URL.
+    function load(url) {
+        return fetch(url)
+            .then(function (resp) {
+                if (!resp.ok) {
+                    throw new Error('fetching search bundle: HTTP ' + resp.status)
+                }
+                return resp.json()
+            })
+            .then(function (bundle) {
+                if (bundle.formatVersion !== FORMAT_VERSION) {
+                    throw new Error('unsupported search bundle format version ' + bundle.formatVersion)
+                }
+                return bundle
+            })
+    }
+
+    // normalize returns the word in lowercase without diacritics (like analysis.Normalize).
+    function normalize(bundle, word) {
+        var s = ''
+        for (var ch of word) {
+            if (MARK.test(ch)) {
+                continue
+            }
+            ch = ch.toLowerCase()
+            s += bundle.foldings[ch] || ch
+        }
+        return s
+    }
+
+    // analyze returns the term of the word.
+    function analyze(bundle, word) {
+        var normalized = normalize(bundle, word)
+        return bundle.words[normalized] || normalized
+    }
+
+    // parse returns the required and excluded terms of the query. Each term is an object with the
+    // list of indexed terms it matches.
+    function parse(bundle, queryStr) {
+        var required = []
+        var excluded = []
+        var stopWords = []
+        queryStr.split(/\s+/).forEach(function (field) {
+            var negated = field.charAt(0) === '-'
+            if (negated) {
+                field = field.slice(1)
+            }
+            field = field.replace(/^[a-z]+:/, '')
+            var prefix = /\*$/.test(field)
+            var words = field.match(WORD) || []
+            words.forEach(function (word, i) {
+                var term
+                if (prefix && i === words.length - 1) {
+                    var p = normalize(bundle, word)
+                    term = {
+                        text: p,
+                        terms: Object.keys(bundle.terms).filter(function (t) {
+                            return t.startsWith(p)
+                        }),
+                    }
+                } else {
+                    var t = analyze(bundle, word)
+                    term = { text: t, terms: bundle.terms[t] ? [t] : [] }
+                    if (!negated && bundle.stopTerms.indexOf(t) !== -1) {
+                        stopWords.push(term)
+                        return
+                    }
+                }
+                ;(negated ? excluded : required).push(term)
+            })
+        })
+        if (required.length === 0) {
+            // Search for stop words only if the query has no other words.
+            required = stopWords
+        }
+        return { required: required, excluded: excluded }
+    }
+
+    // weights returns the weight of the term's occurrences in each section of each document, as a
+    // map from document index to a map from section index to weight.
+    function weights(bundle, term) {
+        var docs = new Map()
+        term.terms.forEach(function (t) {
+            var triples = bundle.terms[t]
+            for (var i = 0; i < triples.length; i += 3) {
+                var doc = triples[i]
+                var sections = docs.get(doc)
+                if (!sections) {
+                    sections = new Map()
+                    docs.set(doc, sections)
+                }
+                sections.set(triples[i + 1], (sections.get(triples[i + 1]) || 0) + triples[i + 2])
+            }
+        })
+        return docs
+    }
+
+    function sum(sections) {
+        var total = 0
+        sections.forEach(function (weight) {
+            total += weight
+        })
+        return total
+    }
+
+    // search searches the bundle for the query. The options are the offset and limit of the
+    // document results to return, and the selected facet values (categories, tags, and dirs).
+    function search(bundle, queryStr, options) {
+        options = options || {}
+        var offset = options.offset || 0
+        var limit = options.limit || 0
+        var filters = {
+            categories: options.categories || [],
+            tags: options.tags || [],
+            dirs: options.dirs || [],
+        }
+
+        var q = parse(bundle, queryStr)
+        var matches = []
+        if (q.required.length > 0) {
+            var required = q.required.map(function (term) {
+                return weights(bundle, term)
+            })
+            var excluded = q.excluded.map(function (term) {
+                return weights(bundle, term)
+            })
+            var numDocs = bundle.documents.length
+            required[0].forEach(function (_, doc) {
+                if (!required.every(function (w) { return w.has(doc) })) {
+                    return
+                }
+                if (excluded.some(function (w) { return w.has(doc) })) {
+                    return
+                }
+                var score = 0
+                var sections = new Set()
+                required.forEach(function (w) {
+                    var df = w.size
+                    var idf = Math.log(1 + (numDocs - df + 0.5) / (df + 0.5))
+                    var tf = sum(w.get(doc))
+                    score += (idf * tf * (bundle.k1 + 1)) / (tf + bundle.k1)
+                    w.get(doc).forEach(function (_, section) {
+                        sections.add(section)
+                    })
+                })
+                score *= bundle.documents[doc].boost || 1
+                matches.push({ doc: doc, score: score, sections: sections })
+            })
+        }
+        matches.sort(function (a, b) {
+            var idA = bundle.documents[a.doc].id
+            var idB = bundle.documents[b.doc].id
+            return b.score - a.score || (idA < idB ? -1 : idA > idB ? 1 : 0)
+        })
+
+        var facets = filterFacets(bundle, matches, filters)
+        matches = facets.matches
+        var page = limit > 0 ? matches.slice(offset, offset + limit) : matches.slice(offset)
+        return {
+            query: queryStr,
+            contentVersion: bundle.contentVersion,
+            total: matches.length,
+            offset: offset,
+            limit: limit,
+            hasMore: offset + page.length < matches.length,
+            results: page.map(function (m) {
+                return documentResult(bundle, q, m)
+            }),
+            facets: facets.facets,
+        }
+    }
+
+    function documentResult(bundle, q, match) {
+        var doc = bundle.documents[match.doc]
+        var sections = []
+        doc.sections.forEach(function (section, i) {
+            if (!match.sections.has(i)) {
+                return
+            }
+            sections.push({
+                id: section.id,
+                idStack: section.idStack,
+                title: section.title,
+                titleStack: section.titleStack,
+                url: section.id ? doc.url + '#' + section.id : doc.url,
+                excerpts: excerpts(bundle, q, section.text),
+            })
+        })
+        return { id: doc.id, title: doc.title, url: doc.url, score: match.score, sections: sections }
+    }
+
+    // excerpts returns an HTML excerpt of each line of the text that contains any of the query's
+    // required terms, with the matching words wrapped in <strong>.
+    function excerpts(bundle, q, text) {
+        var terms = new Set()
+        q.required.forEach(function (term) {
+            term.terms.forEach(function (t) {
+                terms.add(t)
+            })
+        })
+        var result = []
+        text.split('\n').forEach(function (line) {
+            var ranges = []
+            var m
+            WORD.lastIndex = 0
+            while ((m = WORD.exec(line)) !== null) {
+                if (terms.has(analyze(bundle, m[0]))) {
+                    ranges.push([m.index, m.index + m[0].length])
+                }
+            }
+            if (ranges.length === 0) {
+                return
+            }
+
+            // Show the text around the first match.
+            var start = 0
+            var end = line.length
+            if (end - start > EXCERPT_MAX_LENGTH) {
+                start = Math.max(0, ranges[0][0] - EXCERPT_MAX_LENGTH / 2)
+                end = Math.min(line.length, start + EXCERPT_MAX_LENGTH)
+            }
+            var html = start > 0 ? '…' : ''
+            var pos = start
+            ranges.forEach(function (r) {
+                if (r[0] < pos || r[1] > end) {
+                    return
+                }
+                html += escapeHTML(line.slice(pos, r[0])) + '<strong>' + escapeHTML(line.slice(r[0], r[1])) + '</strong>'
+                pos = r[1]
+            })
+            html += escapeHTML(line.slice(pos, end)) + (end < line.length ? '…' : '')
+            result.push(html)
+        })
+        return result
+    }
+
+    function escapeHTML(s) {
+        return s.replace(/[&<>"']/g, function (c) {
+            return { '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&#34;', "'": '&#39;' }[c]
+        })
+    }
+
+    // filterFacets returns the matches that match the filters, and the facets of all matches (like
+    // the server's facets: the counts of each facet are of the matches of the other facets'
+    // filters).
+    function filterFacets(bundle, matches, filters) {
+        var counts = { categories: new Map(), tags: new Map(), dirs: new Map() }
+        var add = function (m, v) {
+            m.set(v, (m.get(v) || 0) + 1)
+        }
+        var matchFilter = function (selected, values) {
+            return selected.length === 0 || values.some(function (v) { return selected.indexOf(v) !== -1 })
+        }
+        var filtered = matches.filter(function (m) {
+            var doc = bundle.documents[m.doc]
+            var tags = doc.tags || []
+            var categoryOK = matchFilter(filters.categories, [doc.category || ''])
+            var tagOK = matchFilter(filters.tags, tags)
+            var dirOK = matchFilter(filters.dirs, [doc.dir || ''])
+            if (tagOK && dirOK && doc.category) {
+                add(counts.categories, doc.category)
+            }
+            if (categoryOK && dirOK) {
+                new Set(tags).forEach(function (tag) {
+                    add(counts.tags, tag)
+                })
+            }
+            if (categoryOK && tagOK && doc.dir) {
+                add(counts.dirs, doc.dir)
+            }
+            return categoryOK && tagOK && dirOK
+        })
+        var values = function (m, selected) {
+            selected.forEach(function (v) {
+                m.set(v, m.get(v) || 0)
+            })
+            var vs = []
+            m.forEach(function (count, value) {
+                vs.push({ value: value, count: count, selected: selected.indexOf(value) !== -1 })
+            })
+            return vs.sort(function (a, b) {
+                return b.count - a.count || (a.value < b.value ? -1 : a.value > b.value ? 1 : 0)
+            })
+        }
+        return {
+            matches: filtered,
+            facets: {
+                categories: values(counts.categories, filters.categories),
+                tags: values(counts.tags, filters.tags),
+                dirs: values(counts.dirs, filters.dirs),
+            },
+        }
+    }
+
+    var docsiteSearch = { load: load, search: search }
+    if (typeof module === 'object' && module.exports) {
+        module.exports = docsiteSearch
+    } else {
+        root.docsiteSearch = docsiteSearch
+    }
+})(this)
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func init() {
+	flagSet := flag.NewFlagSet("index", flag.ExitOnError)
+
+	buildFlagSet := flag.NewFlagSet("index build", flag.ExitOnError)
+	var (
+		contentVersion = buildFlagSet.String("content-version", "", "version of content to index")
+		outPath        = buildFlagSet.String("out", "", "`path` of the search index file to write (default: the search.indexPath config option)")
+	)
+
+	handler := func(args []string) error {
+		_ = flagSet.Parse(args)
+		if flagSet.Arg(0) != "build" {
+			return &usageError{fmt.Errorf("unknown index subcommand %q (want \"build\")", flagSet.Arg(0))}
+		}
+		_ = buildFlagSet.Parse(flagSet.Args()[1:])
+
+		site, _, err := siteFromFlags()
+		if err != nil {
+			return err
+		}
+		path := *outPath
+		if path == "" {
+			path = site.SearchIndexFile(*contentVersion)
+			if path == "" {
+				return &usageError{fmt.Errorf("no search index file path for content version %q (use -out or the search.indexPath config option)", *contentVersion)}
+			}
+		}
+
+		// Write to a temporary file and rename it, so that a running server never reads a partially
+		// written file.
+		f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		if err := site.WriteSearchIndex(context.Background(), *contentVersion, f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Chmod(0644); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		if err := os.Rename(f.Name(), path); err != nil {
+			return err
+		}
+		log.Printf("wrote search index for content version %q to %s", *contentVersion, path)
+		return nil
+	}
+
+	// Register the command.
+	commands = append(commands, &command{
+		FlagSet:          flagSet,
+		ShortDescription: "build a search index file",
+		LongDescription:  "The index subcommand builds a search index file for a version of the content, so that the site can load it instead of building the search index when it starts (see the search.indexPath config option).\n\nUsage: docsite index build [-content-version version] [-out path]",
+		handler:          handler,
+	})
+}
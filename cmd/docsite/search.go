@@ -7,12 +7,17 @@ import (
 	"log"
 	"os"
 	"strings"
+
+	"github.com/sourcegraph/docsite/internal/search"
 )
 
 func init() {
 	flagSet := flag.NewFlagSet("search", flag.ExitOnError)
 	var (
-		contentVersion = flagSet.String("content-version", "", "version of content to search")
+		contentVersion = flagSet.String("content-version", "", "version of content to search (or \"*\" for all searchable versions)")
+		versions       = flagSet.String("versions", "", "comma-separated `versions` of content to search (instead of -content-version)")
+		offset         = flagSet.Int("offset", 0, "number of (highest-ranked) documents to skip")
+		limit          = flagSet.Int("limit", 0, "maximum number of documents to show (0 for no limit)")
 	)
 
 	handler := func(args []string) error {
@@ -22,25 +27,52 @@ func init() {
 			return err
 		}
 		query := strings.Join(flagSet.Args(), " ")
-		result, err := site.Search(context.Background(), *contentVersion, query)
-		if err != nil {
-			return err
+		opt := search.Options{Offset: *offset, Limit: *limit}
+		var result *search.Result
+		if *contentVersion == "*" || *versions != "" {
+			versionsStr := *versions
+			if *contentVersion == "*" {
+				versionsStr = "*"
+			}
+			contentVersions, err := site.ParseSearchVersions(versionsStr)
+			if err != nil {
+				return err
+			}
+			result, err = site.SearchVersions(context.Background(), contentVersions, query, opt)
+			if err != nil {
+				return err
+			}
+		} else {
+			result, err = site.Search(context.Background(), *contentVersion, query, opt)
+			if err != nil {
+				return err
+			}
 		}
 		if result.Total == 0 {
 			log.Println("no results found")
+			for _, suggestion := range result.Suggestions {
+				log.Printf("did you mean: %s", suggestion)
+			}
 			os.Exit(1)
 		}
 		for _, dr := range result.DocumentResults {
+			var inVersions string
+			if dr.Versions != nil {
+				inVersions = fmt.Sprintf(" [versions: %s]", strings.Join(dr.Versions, ", "))
+			}
 			for _, sr := range dr.SectionResults {
 				var moreExcerpts string
 				if len(sr.Excerpts) >= 2 {
 					moreExcerpts = fmt.Sprintf(" (+%d more)", len(sr.Excerpts)-1)
 				}
 				if len(sr.Excerpts) > 0 {
-					fmt.Printf("%s#%s: %s%s\n", dr.ID, sr.ID, sr.Excerpts[0], moreExcerpts)
+					fmt.Printf("%s#%s: %s%s%s\n", dr.ID, sr.ID, sr.Excerpts[0], moreExcerpts, inVersions)
 				}
 			}
 		}
+		if result.HasMore {
+			log.Printf("showing %d of %d documents (use -offset and -limit to show others)", len(result.DocumentResults), result.Total)
+		}
 		return nil
 	}
 
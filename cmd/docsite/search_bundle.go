@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// searchScript is the client-side script that searches the bundle.
+//
+//go:embed docsite-search.js
+var searchScript []byte
+
+func init() {
+	flagSet := flag.NewFlagSet("search-bundle", flag.ExitOnError)
+	var (
+		contentVersion = flagSet.String("content-version", "", "version of content to index")
+		outDir         = flagSet.String("out", ".", "`dir` to write the search bundle and script to (such as the assets directory of a static export)")
+		bundleName     = flagSet.String("bundle", "search-index.json", "file `name` of the search bundle")
+	)
+
+	handler := func(args []string) error {
+		_ = flagSet.Parse(args)
+		site, _, err := siteFromFlags()
+		if err != nil {
+			return err
+		}
+		bundle, err := site.SearchBundle(context.Background(), *contentVersion)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(bundle)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(*outDir, 0755); err != nil {
+			return err
+		}
+		bundlePath := filepath.Join(*outDir, *bundleName)
+		if err := os.WriteFile(bundlePath, data, 0644); err != nil {
+			return err
+		}
+		scriptPath := filepath.Join(*outDir, "docsite-search.js")
+		if err := os.WriteFile(scriptPath, searchScript, 0644); err != nil {
+			return err
+		}
+		log.Printf("wrote search bundle of %d documents to %s and search script to %s", len(bundle.Documents), bundlePath, scriptPath)
+		return nil
+	}
+
+	// Register the command.
+	commands = append(commands, &command{
+		FlagSet:          flagSet,
+		ShortDescription: "write a search bundle for client-side search",
+		LongDescription:  "The search-bundle subcommand writes the search index of a version of the content as JSON, and a script (docsite-search.js) that searches it in the browser, for sites that are served without a docsite server (such as static exports).",
+		handler:          handler,
+	})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sourcegraph/docsite/internal/search/analytics"
+)
+
+func init() {
+	flagSet := flag.NewFlagSet("search-stats", flag.ExitOnError)
+	var (
+		file = flagSet.String("file", "", "`path` of the search analytics file (default: the search.analytics.path config option)")
+		n    = flagSet.Int("n", 20, "number of top queries to show (0 for all)")
+	)
+
+	handler := func(args []string) error {
+		_ = flagSet.Parse(args)
+		path := *file
+		if path == "" {
+			site, _, err := siteFromFlags()
+			if err != nil {
+				return err
+			}
+			sink, ok := site.SearchAnalytics.(*analytics.FileSink)
+			if !ok {
+				return &usageError{fmt.Errorf("no search analytics file (use -file or the search.analytics.path config option)")}
+			}
+			path = sink.Path
+		}
+
+		events, err := analytics.ReadFiles(path)
+		if err != nil {
+			return err
+		}
+		stats := analytics.Summarize(events, *n)
+		fmt.Printf("%d searches (%d with no results), %d clicks, mean latency %s\n", stats.Searches, stats.ZeroResultSearches, stats.Clicks, stats.MeanLatency.Round(time.Microsecond))
+		printQueryStats("Top queries", stats.TopQueries)
+		printQueryStats("Top queries with no results", stats.ZeroResultQueries)
+		return nil
+	}
+
+	// Register the command.
+	commands = append(commands, &command{
+		FlagSet:          flagSet,
+		ShortDescription: "summarize search analytics",
+		LongDescription:  "The search-stats subcommand summarizes the searches and clicks on search results recorded in the search analytics file (and the files rotated from it), showing the top queries and the top queries with no results.",
+		handler:          handler,
+	})
+}
+
+func printQueryStats(title string, queries []analytics.QueryStats) {
+	fmt.Printf("\n%s:\n", title)
+	if len(queries) == 0 {
+		fmt.Println("  (none)")
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "  SEARCHES\tCLICKS\tQUERY")
+	for _, q := range queries {
+		fmt.Fprintf(tw, "  %d\t%d\t%s\n", q.Searches, q.Clicks, q.Query)
+	}
+	_ = tw.Flush()
+}
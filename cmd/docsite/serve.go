@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"log"
@@ -8,6 +9,8 @@ import (
 	"net/http"
 	"os"
 	"sync"
+
+	"github.com/sourcegraph/docsite"
 )
 
 func init() {
@@ -41,6 +44,10 @@ func init() {
 				log.Fatal(err)
 			}
 			handler = site.Handler()
+
+			if site.SearchIndexPath != "" {
+				go loadSearchIndexes(site)
+			}
 		}()
 
 		l, err := net.Listen("tcp", *httpAddr)
@@ -86,3 +93,20 @@ func init() {
 		handler:          handler,
 	})
 }
+
+// loadSearchIndexes loads the search indexes (from the search index files, if they are up to date)
+// of the default content version and the searchable versions, so that the first searches don't need
+// to wait for them to be built.
+func loadSearchIndexes(site *docsite.Site) {
+	contentVersions := []string{""}
+	for _, v := range site.SearchableVersions {
+		if v != "" {
+			contentVersions = append(contentVersions, v)
+		}
+	}
+	for _, contentVersion := range contentVersions {
+		if err := site.LoadSearchIndex(context.Background(), contentVersion); err != nil {
+			log.Printf("# Error loading search index for content version %q: %s", contentVersion, err)
+		}
+	}
+}
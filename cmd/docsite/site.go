@@ -25,6 +25,8 @@ import (
 	"golang.org/x/tools/godoc/vfs/mapfs"
 
 	"github.com/sourcegraph/docsite"
+	"github.com/sourcegraph/docsite/internal/search/analytics"
+	"github.com/sourcegraph/docsite/internal/search/query"
 )
 
 func siteFromFlags() (*docsite.Site, *docsiteConfig, error) {
@@ -65,8 +67,28 @@ type docsiteConfig struct {
 	Check                       struct {
 		IgnoreURLPattern string
 	}
+	Sitemap struct {
+		Versions []string
+	}
+	Feeds  map[string]string
+	Robots struct {
+		Rules           []docsite.RobotsRule
+		IndexedVersions []string
+	}
 	Search struct {
 		SkipIndexURLPattern string
+		K1                  *float64
+		B                   *float64
+		FieldBoosts         map[string]float64
+		SynonymBoost        *float64
+		Versions            []string
+		BestBets            map[string]string
+		IndexPath           string
+		Analytics           struct {
+			Path      string
+			MaxSizeMB int64
+			MaxFiles  int
+		}
 	}
 }
 
@@ -112,6 +134,40 @@ func partialSiteFromConfig(config docsiteConfig) (*docsite.Site, error) {
 			return nil, err
 		}
 	}
+	if config.Search.K1 != nil || config.Search.B != nil || config.Search.FieldBoosts != nil || config.Search.SynonymBoost != nil {
+		params := query.DefaultScoreParams()
+		if config.Search.K1 != nil {
+			params.K1 = *config.Search.K1
+		}
+		if config.Search.B != nil {
+			params.B = *config.Search.B
+		}
+		if config.Search.SynonymBoost != nil {
+			params.SynonymBoost = *config.Search.SynonymBoost
+		}
+		for name, boost := range config.Search.FieldBoosts {
+			field, ok := query.FieldByName(name)
+			if !ok {
+				return nil, fmt.Errorf("invalid search field boost for unknown field %q", name)
+			}
+			params.Boosts[field] = boost
+		}
+		site.SearchScoreParams = &params
+	}
+	site.SearchableVersions = config.Search.Versions
+	site.SearchBestBets = config.Search.BestBets
+	site.SitemapVersions = config.Sitemap.Versions
+	site.Feeds = config.Feeds
+	site.RobotsRules = config.Robots.Rules
+	site.IndexedVersions = config.Robots.IndexedVersions
+	site.SearchIndexPath = config.Search.IndexPath
+	if config.Search.Analytics.Path != "" {
+		site.SearchAnalytics = &analytics.FileSink{
+			Path:     config.Search.Analytics.Path,
+			MaxSize:  config.Search.Analytics.MaxSizeMB << 20,
+			MaxFiles: config.Search.Analytics.MaxFiles,
+		}
+	}
 
 	for fromPath, toURLStr := range config.Redirects {
 		if err := addSiteRedirect(&site, fromPath, toURLStr); err != nil {
@@ -206,6 +262,12 @@ func openDocsiteFromConfig(configData []byte, baseDir string) (*docsite.Site, *d
 	} else {
 		site.Content = nonVersionedFileSystem{httpDirOrNil(config.Content)}
 	}
+	if config.Search.IndexPath != "" && !filepath.IsAbs(config.Search.IndexPath) {
+		site.SearchIndexPath = filepath.Join(baseDir, config.Search.IndexPath)
+	}
+	if sink, ok := site.SearchAnalytics.(*analytics.FileSink); ok && !filepath.IsAbs(sink.Path) {
+		sink.Path = filepath.Join(baseDir, sink.Path)
+	}
 
 	if err := addRedirectsFromAssets(site); err != nil {
 		return nil, nil, err
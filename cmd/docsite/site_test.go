@@ -3,9 +3,15 @@ package main
 import (
 	"archive/zip"
 	"bytes"
+	"encoding/json"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+
+	"github.com/sourcegraph/docsite"
+	"github.com/sourcegraph/docsite/internal/search/analytics"
+	"github.com/sourcegraph/docsite/internal/search/query"
 )
 
 func TestMapFromZipArchive(t *testing.T) {
@@ -92,3 +98,113 @@ func TestMapFromZipArchive(t *testing.T) {
 		}
 	})
 }
+
+func TestPartialSiteFromConfig(t *testing.T) {
+	t.Run("search score params", func(t *testing.T) {
+		var config docsiteConfig
+		if err := json.Unmarshal([]byte(`{"search":{"b":0,"fieldBoosts":{"code":2},"synonymBoost":0.25}}`), &config); err != nil {
+			t.Fatal(err)
+		}
+		site, err := partialSiteFromConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := query.DefaultScoreParams()
+		want.B = 0
+		want.Boosts[query.FieldCode] = 2
+		want.SynonymBoost = 0.25
+		if !reflect.DeepEqual(site.SearchScoreParams, &want) {
+			t.Errorf("got %+v, want %+v", site.SearchScoreParams, want)
+		}
+	})
+
+	t.Run("search versions", func(t *testing.T) {
+		var config docsiteConfig
+		if err := json.Unmarshal([]byte(`{"search":{"versions":["", "3.0"]}}`), &config); err != nil {
+			t.Fatal(err)
+		}
+		site, err := partialSiteFromConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"", "3.0"}; !reflect.DeepEqual(site.SearchableVersions, want) {
+			t.Errorf("got %q, want %q", site.SearchableVersions, want)
+		}
+	})
+
+	t.Run("sitemap versions", func(t *testing.T) {
+		var config docsiteConfig
+		if err := json.Unmarshal([]byte(`{"sitemap":{"versions":["3.0"]}}`), &config); err != nil {
+			t.Fatal(err)
+		}
+		site, err := partialSiteFromConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"3.0"}; !reflect.DeepEqual(site.SitemapVersions, want) {
+			t.Errorf("got %q, want %q", site.SitemapVersions, want)
+		}
+	})
+
+	t.Run("feeds", func(t *testing.T) {
+		var config docsiteConfig
+		if err := json.Unmarshal([]byte(`{"feeds":{"releases":"releases/feed"}}`), &config); err != nil {
+			t.Fatal(err)
+		}
+		site, err := partialSiteFromConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]string{"releases": "releases/feed"}; !reflect.DeepEqual(site.Feeds, want) {
+			t.Errorf("got %q, want %q", site.Feeds, want)
+		}
+	})
+
+	t.Run("robots", func(t *testing.T) {
+		var config docsiteConfig
+		if err := json.Unmarshal([]byte(`{"robots":{"rules":[{"userAgent":"*","disallow":["/@"]}],"indexedVersions":[""]}}`), &config); err != nil {
+			t.Fatal(err)
+		}
+		site, err := partialSiteFromConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []docsite.RobotsRule{{UserAgent: "*", Disallow: []string{"/@"}}}; !reflect.DeepEqual(site.RobotsRules, want) {
+			t.Errorf("got rules %+v, want %+v", site.RobotsRules, want)
+		}
+		if want := []string{""}; !reflect.DeepEqual(site.IndexedVersions, want) {
+			t.Errorf("got indexed versions %q, want %q", site.IndexedVersions, want)
+		}
+	})
+
+	t.Run("search index path", func(t *testing.T) {
+		dir := t.TempDir()
+		site, _, err := openDocsiteFromConfig([]byte(`{"content":".","search":{"indexPath":"index-$VERSION.gz"}}`), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filepath.Join(dir, "index-$VERSION.gz"); site.SearchIndexPath != want {
+			t.Errorf("got %q, want %q", site.SearchIndexPath, want)
+		}
+	})
+
+	t.Run("search analytics", func(t *testing.T) {
+		dir := t.TempDir()
+		site, _, err := openDocsiteFromConfig([]byte(`{"content":".","search":{"analytics":{"path":"search.jsonl","maxSizeMB":2,"maxFiles":3}}}`), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &analytics.FileSink{Path: filepath.Join(dir, "search.jsonl"), MaxSize: 2 << 20, MaxFiles: 3}
+		if !reflect.DeepEqual(site.SearchAnalytics, want) {
+			t.Errorf("got %+v, want %+v", site.SearchAnalytics, want)
+		}
+	})
+
+	t.Run("unknown search field", func(t *testing.T) {
+		var config docsiteConfig
+		config.Search.FieldBoosts = map[string]float64{"x": 1}
+		if _, err := partialSiteFromConfig(config); err == nil {
+			t.Error("got nil error, want error")
+		}
+	})
+}
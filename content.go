@@ -3,7 +3,6 @@ package docsite
 import (
 	"net/http"
 	"os"
-	pathpkg "path"
 	"path/filepath"
 	"strings"
 
@@ -16,7 +15,23 @@ type ContentPage struct {
 	FilePath    string            // the filename on disk
 	Data        []byte            // the page's file contents
 	Doc         markdown.Document // the Markdown doc
-	Breadcrumbs []breadcrumbEntry // ancestor breadcrumb for this page
+	Breadcrumbs []breadcrumbEntry // ancestor breadcrumb for this page (only set for pages rendered by the handler)
+
+	// Prev, Next, Parent, and Siblings link to the pages around the page in the navigation tree (see
+	// Site.NavTree): the previous and next pages in its directory, the directory's index page, and
+	// all pages in its directory (including the page itself, which is marked as active). They are
+	// only set for pages rendered by the handler.
+	Prev     *PageLink
+	Next     *PageLink
+	Parent   *PageLink
+	Siblings []PageLink
+}
+
+// PageLink is a link to a content page.
+type PageLink struct {
+	Title  string // the page title
+	URL    string // the page URL
+	Active bool   // whether it is the current page
 }
 
 func contentFilePathToPath(filePath string) string {
@@ -51,27 +66,28 @@ func isDir(fs http.FileSystem, path string) bool {
 
 type breadcrumbEntry struct {
 	Label    string
-	URL      string
+	URL      string // empty for a directory without an index page
 	IsActive bool
 }
 
-func makeBreadcrumbEntries(path string) []breadcrumbEntry {
-	if path == "" {
+// makeBreadcrumbEntries returns the breadcrumb entries for the nodes from the root of the navigation
+// tree to the current page. Each entry is labeled with the title of the page (the index page, for a
+// directory), and the root is labeled "Documentation" if its index page has no title.
+func makeBreadcrumbEntries(nodes []*NavNode) []breadcrumbEntry {
+	if len(nodes) < 2 {
 		return nil
 	}
-	parts := strings.Split(path, "/")
-	entries := make([]breadcrumbEntry, len(parts)+1)
-	entries[0] = breadcrumbEntry{
-		Label: "Documentation",
-		URL:   "/",
-	}
-	for i, part := range parts {
-		entries[i+1] = breadcrumbEntry{
-			Label:    part,
-			URL:      "/" + pathpkg.Join(parts[:i+1]...),
-			IsActive: i == len(parts)-1,
+	entries := make([]breadcrumbEntry, len(nodes))
+	for i, n := range nodes {
+		entries[i] = breadcrumbEntry{
+			Label:    n.Title,
+			URL:      n.URL,
+			IsActive: i == len(nodes)-1,
 		}
 	}
+	if entries[0].Label == "" {
+		entries[0].Label = "Documentation"
+	}
 	return entries
 }
 
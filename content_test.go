@@ -1,8 +1,13 @@
 package docsite
 
 import (
+	"context"
+	"net/url"
 	"reflect"
 	"testing"
+
+	"golang.org/x/tools/godoc/vfs/httpfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
 )
 
 func TestContentFilePathToPath(t *testing.T) {
@@ -21,24 +26,71 @@ func TestContentFilePathToPath(t *testing.T) {
 }
 
 func TestMakeBreadcrumbEntries(t *testing.T) {
-	tests := map[string][]breadcrumbEntry{
-		"a/b/c": []breadcrumbEntry{
-			{Label: "Documentation", URL: "/", IsActive: false},
-			{Label: "a", URL: "/a", IsActive: false},
-			{Label: "b", URL: "/a/b", IsActive: false},
-			{Label: "c", URL: "/a/b/c", IsActive: true},
+	site := Site{
+		Content: versionedFileSystem{
+			"": httpfs.New(mapfs.New(map[string]string{
+				"index.md":     "# Home",
+				"a/index.md":   "# Admin",
+				"a/b/c.md":     "# Config",
+				"a/b/c/d.md":   "# D",
+				"e/index.md":   "# E",
+				"e/f/index.md": "# F",
+			})),
+			"v1": httpfs.New(mapfs.New(map[string]string{
+				"a/index.md": "# Admin",
+				"a/b.md":     "b",
+			})),
+		},
+		Base: &url.URL{Path: "/help/"},
+	}
+
+	tests := map[string]struct {
+		version string
+		want    []breadcrumbEntry
+	}{
+		"a/b/c": {
+			want: []breadcrumbEntry{
+				{Label: "Home", URL: "/help/"},
+				{Label: "Admin", URL: "/help/a"},
+				{Label: "b"},
+				{Label: "Config", URL: "/help/a/b/c", IsActive: true},
+			},
 		},
-		"a": []breadcrumbEntry{
-			{Label: "Documentation", URL: "/", IsActive: false},
-			{Label: "a", URL: "/a", IsActive: true},
+		"a/b/c/d": {
+			want: []breadcrumbEntry{
+				{Label: "Home", URL: "/help/"},
+				{Label: "Admin", URL: "/help/a"},
+				{Label: "b"},
+				{Label: "c"},
+				{Label: "D", URL: "/help/a/b/c/d", IsActive: true},
+			},
 		},
-		"": nil,
+		"e/f": {
+			want: []breadcrumbEntry{
+				{Label: "Home", URL: "/help/"},
+				{Label: "E", URL: "/help/e"},
+				{Label: "F", URL: "/help/e/f", IsActive: true},
+			},
+		},
+		"a/b": {
+			version: "v1",
+			want: []breadcrumbEntry{
+				{Label: "Documentation"},
+				{Label: "Admin", URL: "/help/@v1/a"},
+				{Label: "b", URL: "/help/@v1/a/b", IsActive: true},
+			},
+		},
+		"": {want: nil},
 	}
-	for path, want := range tests {
+	for path, test := range tests {
 		t.Run(path, func(t *testing.T) {
-			got := makeBreadcrumbEntries(path)
-			if !reflect.DeepEqual(got, want) {
-				t.Errorf("got %+v, want %+v", got, want)
+			tree, err := site.NavTree(context.Background(), test.version, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := makeBreadcrumbEntries(tree.activePath())
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
 			}
 		})
 	}
@@ -0,0 +1,316 @@
+package docsite
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/docsite/markdown"
+)
+
+// Feed formats (which are also the file extensions of the feeds' URL paths).
+const (
+	FeedAtom = "atom" // an Atom feed (see RFC 4287)
+	FeedRSS  = "rss"  // an RSS 2.0 feed
+
+	atomNamespace       = "http://www.w3.org/2005/Atom"
+	rssContentNamespace = "http://purl.org/rss/1.0/modules/content/"
+)
+
+var errFeedNoRoot = errors.New("feeds require the site's root URL")
+
+// atomFeed is an Atom feed.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Links   []atomLink  `xml:"link"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// atomPerson is the author of an Atom feed, which RFC 4287 requires (on the feed or on every
+// entry).
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Link    atomLink    `xml:"link"`
+	Updated string      `xml:"updated"`
+	Summary string      `xml:"summary,omitempty"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// rssFeed is an RSS 2.0 feed, with the items' HTML content in the content module's
+// <content:encoded> element.
+type rssFeed struct {
+	XMLName      xml.Name   `xml:"rss"`
+	Version      string     `xml:"version,attr"`
+	XMLNSContent string     `xml:"xmlns:content,attr"`
+	Channel      rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description,omitempty"`
+	Content     string  `xml:"content:encoded"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	ID          string `xml:",chardata"`
+}
+
+// feedEntry is a page in a feed.
+type feedEntry struct {
+	page *ContentPage // the page, rendered with absolute URLs (see absoluteContentPage)
+	url  string       // the absolute URL of the page
+	time time.Time    // the time that the page was published
+}
+
+// Feed returns the feed (in the format FeedAtom or FeedRSS) of the pages in the content directory
+// (and its subdirectories) of the content version, titled by the directory's index page. The
+// entries are ordered from newest to oldest by the pages' "date" metadata (or, if they have none,
+// their "lastUpdated" metadata or file modification time), and contain the pages' HTML with
+// absolute URLs (using Root).
+//
+// Only the pages in the directory are read. The feed is built once and reused until the version's
+// file system is replaced or its files change (see contentStamp).
+func (s *Site) Feed(ctx context.Context, contentVersion, dir, format string) ([]byte, error) {
+	if format != FeedAtom && format != FeedRSS {
+		return nil, fmt.Errorf("invalid feed format %q", format)
+	}
+	if s.Root == nil {
+		return nil, errFeedNoRoot
+	}
+	content, err := s.Content.OpenVersion(ctx, contentVersion)
+	if err != nil {
+		return nil, err
+	}
+	stamp, err := s.contentStamp(contentVersion, content)
+	if err != nil {
+		return nil, err
+	}
+	dir = strings.Trim(dir, "/")
+	key := contentVersion + "\x00" + dir + "\x00" + format
+	return s.feeds.get(key, content, stamp, func() ([]byte, error) {
+		return s.buildFeed(ctx, content, contentVersion, dir, format)
+	})
+}
+
+// buildFeed builds the feed of the pages in the content directory of the file system.
+func (s *Site) buildFeed(ctx context.Context, content http.FileSystem, contentVersion, dir, format string) ([]byte, error) {
+	allPaths, err := s.contentPagePaths(content)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, path := range allPaths {
+		pagePath := contentFilePathToPath(path)
+		if dir == "" || pagePath == dir || strings.HasPrefix(pagePath, dir+"/") {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, &os.PathError{Op: "open", Path: dir, Err: os.ErrNotExist}
+	}
+
+	pages := make([]*ContentPage, len(paths))
+	err = forEachParallel(ctx, len(paths), func(ctx context.Context, i int) error {
+		data, err := ReadFile(content, paths[i])
+		if err != nil {
+			return err
+		}
+		pages[i], err = s.absoluteContentPage(paths[i], data, contentVersion)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	title := dir
+	if dir == "" {
+		title = "Documentation"
+	}
+	var entries []feedEntry
+	for _, page := range pages {
+		if page.Path == dir {
+			if page.Doc.Title != "" {
+				title = page.Doc.Title
+			}
+			continue
+		}
+		t, ok, err := feedEntryTime(content, page)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		entries = append(entries, feedEntry{
+			page: page,
+			url:  s.absoluteURL(versionedPath(contentVersion, page.Path)),
+			time: t,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].time.Equal(entries[j].time) {
+			return entries[i].time.After(entries[j].time)
+		}
+		return entries[i].page.Path < entries[j].page.Path
+	})
+
+	var updated time.Time
+	if len(entries) > 0 {
+		updated = entries[0].time
+	}
+	dirURL := s.absoluteURL(versionedPath(contentVersion, dir))
+	if format == FeedAtom {
+		feed := atomFeed{
+			XMLNS: atomNamespace,
+			Title: title,
+			ID:    dirURL,
+			Links: []atomLink{
+				{Href: s.absoluteURL(versionedPath(contentVersion, s.feedURLPath(dir)+"."+FeedAtom)), Rel: "self"},
+				{Href: dirURL},
+			},
+			Updated: updated.UTC().Format(time.RFC3339),
+			// The site is the author of its pages (which have no authors of their own).
+			Author: atomPerson{Name: s.Root.Host, URI: s.absoluteURL("")},
+		}
+		for _, e := range entries {
+			feed.Entries = append(feed.Entries, atomEntry{
+				Title:   e.page.Doc.Title,
+				ID:      e.url,
+				Link:    atomLink{Href: e.url},
+				Updated: e.time.UTC().Format(time.RFC3339),
+				Summary: e.page.Doc.Meta.Description,
+				Content: atomContent{Type: "html", Body: string(e.page.Doc.HTML)},
+			})
+		}
+		return marshalXML(feed)
+	}
+
+	feed := rssFeed{
+		Version:      "2.0",
+		XMLNSContent: rssContentNamespace,
+		Channel: rssChannel{
+			Title:       title,
+			Link:        dirURL,
+			Description: title,
+		},
+	}
+	if !updated.IsZero() {
+		feed.Channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, e := range entries {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       e.page.Doc.Title,
+			Link:        e.url,
+			GUID:        rssGUID{IsPermaLink: true, ID: e.url},
+			PubDate:     e.time.UTC().Format(time.RFC1123Z),
+			Description: e.page.Doc.Meta.Description,
+			Content:     string(e.page.Doc.HTML),
+		})
+	}
+	return marshalXML(feed)
+}
+
+// feedAtURLPath returns the content directory and format of the feed at the URL path (relative to
+// Base and the content version), if any.
+func (s *Site) feedAtURLPath(urlPath string) (dir, format string, ok bool) {
+	ext := strings.TrimPrefix(pathpkg.Ext(urlPath), ".")
+	if ext != FeedAtom && ext != FeedRSS {
+		return "", "", false
+	}
+	for dir, feedPath := range s.Feeds {
+		if strings.Trim(feedPath, "/")+"."+ext == urlPath {
+			return strings.Trim(dir, "/"), ext, true
+		}
+	}
+	return "", "", false
+}
+
+// feedURLPath returns the URL path (relative to Base and the content version, and without a file
+// extension) of the feed of the content directory.
+func (s *Site) feedURLPath(dir string) string {
+	for d, feedPath := range s.Feeds {
+		if strings.Trim(d, "/") == dir {
+			return strings.Trim(feedPath, "/")
+		}
+	}
+	return pathpkg.Join(dir, "feed")
+}
+
+// feedEntryTime returns the time that the page was published for its feed entry. If the page's
+// date metadata is invalid, it is logged and ok is false, so that the page is omitted instead of
+// breaking the feed.
+func feedEntryTime(content http.FileSystem, page *ContentPage) (t time.Time, ok bool, err error) {
+	field, value := "date", page.Doc.Meta.Date
+	if value == "" {
+		field, value = "lastUpdated", page.Doc.Meta.LastUpdated
+	}
+	if value == "" {
+		t, err := fileModTime(content, page.FilePath)
+		return t, err == nil, err
+	}
+	t, err = parseMetadataTime(page, field, value)
+	if err != nil {
+		log.Printf("# Omitting feed entry: %s", err)
+		return time.Time{}, false, nil
+	}
+	return t, true, nil
+}
+
+// absoluteContentPage creates a ContentPage whose HTML has absolute URLs (using Root) in its links
+// and images, so that it can be shown outside of the site (such as in a feed reader).
+func (s *Site) absoluteContentPage(filePath string, data []byte, contentVersion string) (*ContentPage, error) {
+	opt := s.markdownOptions(filePath, contentVersion)
+	opt.Base = s.Root.ResolveReference(opt.Base)
+	doc, err := markdown.Run(data, opt)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("run Markdown for %s", filePath))
+	}
+	return &ContentPage{
+		Path:     contentFilePathToPath(filePath),
+		FilePath: filePath,
+		Data:     data,
+		Doc:      *doc,
+	}, nil
+}
@@ -0,0 +1,268 @@
+package docsite
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/godoc/vfs/httpfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func TestSite_Feed(t *testing.T) {
+	site := Site{
+		Content: versionedFileSystem{
+			"": httpfs.New(mapfs.New(map[string]string{
+				"index.md":          "# Home",
+				"releases/index.md": "# Release notes",
+				"releases/v1.md":    "---\ndate: 2023-01-02\ndescription: The first release\n---\n# Version 1\n\nSee [version 2](v2.md).",
+				"releases/v2.md":    "---\ndate: 2023-02-03T10:00:00+02:00\n---\n# Version 2\n\n![logo](img/logo.png)",
+			})),
+		},
+		Base:  &url.URL{Path: "/help/"},
+		Root:  &url.URL{Scheme: "https", Host: "example.com"},
+		Feeds: map[string]string{"releases": "releases/feed"},
+	}
+	ctx := context.Background()
+
+	// The pages' links and images are resolved to absolute URLs.
+	wantHTML := map[string]string{
+		"https://example.com/help/releases/v1": `<a href="https://example.com/help/releases/v2">version 2</a>`,
+		"https://example.com/help/releases/v2": `<img src="https://example.com/help/releases/img/logo.png" alt="logo">`,
+	}
+
+	t.Run("atom", func(t *testing.T) {
+		data, err := site.Feed(ctx, "", "releases", FeedAtom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var feed atomFeed
+		if err := xml.Unmarshal(data, &feed); err != nil {
+			t.Fatal(err)
+		}
+		for i, e := range feed.Entries {
+			if e.Content.Type != "html" || !strings.Contains(e.Content.Body, wantHTML[e.ID]) {
+				t.Errorf("got entry %s content %+v, want HTML containing %q", e.ID, e.Content, wantHTML[e.ID])
+			}
+			feed.Entries[i].Content = atomContent{}
+		}
+		want := atomFeed{
+			XMLName: xml.Name{Space: atomNamespace, Local: "feed"},
+			XMLNS:   atomNamespace,
+			Title:   "Release notes",
+			ID:      "https://example.com/help/releases",
+			Links: []atomLink{
+				{Href: "https://example.com/help/releases/feed.atom", Rel: "self"},
+				{Href: "https://example.com/help/releases"},
+			},
+			Updated: "2023-02-03T08:00:00Z",
+			Author:  atomPerson{Name: "example.com", URI: "https://example.com/help/"},
+			Entries: []atomEntry{
+				{
+					Title:   "Version 2",
+					ID:      "https://example.com/help/releases/v2",
+					Link:    atomLink{Href: "https://example.com/help/releases/v2"},
+					Updated: "2023-02-03T08:00:00Z",
+				},
+				{
+					Title:   "Version 1",
+					ID:      "https://example.com/help/releases/v1",
+					Link:    atomLink{Href: "https://example.com/help/releases/v1"},
+					Updated: "2023-01-02T00:00:00Z",
+					Summary: "The first release",
+				},
+			},
+		}
+		if diff := cmp.Diff(want, feed); diff != "" {
+			t.Errorf("feed mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("rss", func(t *testing.T) {
+		data, err := site.Feed(ctx, "", "releases", FeedRSS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">`; !strings.Contains(string(data), want) {
+			t.Errorf("got feed %s, want it to contain %q", data, want)
+		}
+		var feed struct {
+			Channel struct {
+				Title         string `xml:"title"`
+				Link          string `xml:"link"`
+				LastBuildDate string `xml:"lastBuildDate"`
+				Items         []struct {
+					Title       string `xml:"title"`
+					Link        string `xml:"link"`
+					GUID        string `xml:"guid"`
+					PubDate     string `xml:"pubDate"`
+					Description string `xml:"description"`
+					Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+				} `xml:"item"`
+			} `xml:"channel"`
+		}
+		if err := xml.Unmarshal(data, &feed); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := feed.Channel.Title, "Release notes"; got != want {
+			t.Errorf("got title %q, want %q", got, want)
+		}
+		if got, want := feed.Channel.LastBuildDate, "Fri, 03 Feb 2023 08:00:00 +0000"; got != want {
+			t.Errorf("got lastBuildDate %q, want %q", got, want)
+		}
+		if len(feed.Channel.Items) != 2 {
+			t.Fatalf("got %d items, want 2", len(feed.Channel.Items))
+		}
+		item := feed.Channel.Items[1]
+		if got, want := []string{item.Title, item.Link, item.GUID, item.PubDate, item.Description}, []string{
+			"Version 1",
+			"https://example.com/help/releases/v1",
+			"https://example.com/help/releases/v1",
+			"Mon, 02 Jan 2023 00:00:00 +0000",
+			"The first release",
+		}; !cmp.Equal(got, want) {
+			t.Errorf("got item %q, want %q", got, want)
+		}
+		if want := wantHTML[item.Link]; !strings.Contains(item.Content, want) {
+			t.Errorf("got item content %q, want it to contain %q", item.Content, want)
+		}
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		site := Site{
+			Content: versionedFileSystem{"": httpfs.New(mapfs.New(map[string]string{
+				"a.md": "---\ndate: yesterday\n---\n# A",
+				"b.md": "---\ndate: 2023-01-02\n---\n# B",
+			}))},
+			Root: &url.URL{Scheme: "https", Host: "example.com"},
+		}
+		data, err := site.Feed(ctx, "", "", FeedAtom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var feed atomFeed
+		if err := xml.Unmarshal(data, &feed); err != nil {
+			t.Fatal(err)
+		}
+		if len(feed.Entries) != 1 || feed.Entries[0].Title != "B" {
+			t.Errorf("got entries %+v, want only B", feed.Entries)
+		}
+	})
+
+	t.Run("pages outside the directory are not rendered", func(t *testing.T) {
+		site := Site{
+			Content: versionedFileSystem{"": httpfs.New(mapfs.New(map[string]string{
+				"releases/v1.md": "# Version 1",
+				"broken.md":      "<div markdown-func=x>b</div>",
+			}))},
+			Root: &url.URL{Scheme: "https", Host: "example.com"},
+		}
+		if _, err := site.Feed(ctx, "", "releases", FeedAtom); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		files := map[string]string{"releases/v1.md": "# Version 1"}
+		content := versionedFileSystem{"": httpfs.New(mapfs.New(files))}
+		site := Site{Content: content, Root: &url.URL{Scheme: "https", Host: "example.com"}}
+		feed := func() string {
+			t.Helper()
+			data, err := site.Feed(ctx, "", "releases", FeedRSS)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(data)
+		}
+
+		if got := feed(); !strings.Contains(got, "Version 1") {
+			t.Fatalf("got feed %s, want it to contain version 1", got)
+		}
+		files["releases/v2.md"] = "# Version 2" // the file system is not replaced, so the cached feed is reused
+		if got := feed(); strings.Contains(got, "Version 2") {
+			t.Errorf("got feed %s, want the cached feed", got)
+		}
+		content[""] = httpfs.New(mapfs.New(files))
+		if got := feed(); !strings.Contains(got, "Version 2") {
+			t.Errorf("got feed %s, want it rebuilt with version 2", got)
+		}
+	})
+
+	t.Run("directory not found", func(t *testing.T) {
+		if _, err := site.Feed(ctx, "", "blog", FeedAtom); !os.IsNotExist(err) {
+			t.Errorf("got error %v, want not-exist error", err)
+		}
+	})
+}
+
+func TestSite_Handler_feed(t *testing.T) {
+	site := Site{
+		Content: versionedFileSystem{
+			"":   httpfs.New(mapfs.New(map[string]string{"releases/a.md": "---\ndate: 2023-01-02\n---\n# A"})),
+			"v1": httpfs.New(mapfs.New(map[string]string{"releases/b.md": "---\ndate: 2023-01-02\n---\n# B"})),
+			"v2": httpfs.New(mapfs.New(map[string]string{"c.md": "# C"})),
+		},
+		Base:  &url.URL{Path: "/"},
+		Root:  &url.URL{Scheme: "https", Host: "example.com"},
+		Feeds: map[string]string{"releases": "releases/feed"},
+	}
+	handler := site.Handler()
+
+	tests := map[string]struct {
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		"/releases/feed.atom": {
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/atom+xml; charset=utf-8",
+			wantBody:        "<id>https://example.com/releases/a</id>",
+		},
+		"/releases/feed.rss": {
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/rss+xml; charset=utf-8",
+			wantBody:        "<link>https://example.com/releases/a</link>",
+		},
+		"/@v1/releases/feed.atom": {
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/atom+xml; charset=utf-8",
+			wantBody:        "<id>https://example.com/@v1/releases/b</id>",
+		},
+		"/@v2/releases/feed.atom": {wantStatus: http.StatusNotFound},
+	}
+	for path, test := range tests {
+		t.Run(path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", path, nil)
+			handler.ServeHTTP(rr, req)
+			if rr.Code != test.wantStatus {
+				t.Fatalf("got HTTP status %d, want %d", rr.Code, test.wantStatus)
+			}
+			if test.wantStatus != http.StatusOK {
+				return
+			}
+			if got := rr.Header().Get("Content-Type"); got != test.wantContentType {
+				t.Errorf("got Content-Type %q, want %q", got, test.wantContentType)
+			}
+			if !strings.Contains(rr.Body.String(), test.wantBody) {
+				t.Errorf("got body %q, want it to contain %q", rr.Body.String(), test.wantBody)
+			}
+		})
+	}
+
+	t.Run("no root URL", func(t *testing.T) {
+		site := Site{Content: site.Content, Base: &url.URL{Path: "/"}, Feeds: site.Feeds}
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/releases/feed.atom", nil)
+		site.Handler().ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("got HTTP status %d, want %d", rr.Code, http.StatusNotFound)
+		}
+	})
+}
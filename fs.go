@@ -2,11 +2,13 @@ package docsite
 
 import (
 	"fmt"
+	"hash/fnv"
 	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -57,3 +59,69 @@ func WalkFileSystem(fs http.FileSystem, filterFn func(path string) bool, walkFn
 	}
 	return nil
 }
+
+// fileSystemStamp returns a string that changes when any file in the file system is added, removed,
+// or modified (as determined by its size and modification time).
+func fileSystemStamp(fs http.FileSystem) (string, error) {
+	h := fnv.New64a()
+	err := WalkFileSystem(fs, func(string) bool { return true }, func(path string) error {
+		f, err := fs.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(h, "%s %d %d\n", path, fi.Size(), fi.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum64()), nil
+}
+
+// localStampInterval is the minimum time between walks of a local content directory (http.Dir) to
+// check whether its files changed.
+var localStampInterval = 2 * time.Second
+
+// contentStampEntry is the stamp of a version of the content.
+type contentStampEntry struct {
+	content http.FileSystem // the content file system that was stamped
+	stamp   string
+	at      time.Time // when the stamp was computed
+}
+
+// contentStamp returns the stamp (see fileSystemStamp) of the content version's file system, which
+// is computed once and reused so that checking whether data built from the content is up to date
+// doesn't walk all of its files.
+//
+// Other file systems than local directories (such as those downloaded from URLs) are replaced by a
+// new file system value when their content changes (see sameFileSystem), so their stamp is reused
+// for as long as they are. The stamp of a local directory is recomputed at most once per
+// localStampInterval.
+func (s *Site) contentStamp(contentVersion string, content http.FileSystem) (string, error) {
+	s.contentStampsMu.Lock()
+	e := s.contentStamps[contentVersion]
+	s.contentStampsMu.Unlock()
+	if e != nil && sameFileSystem(e.content, content) {
+		if _, local := content.(http.Dir); !local || time.Since(e.at) < localStampInterval {
+			return e.stamp, nil
+		}
+	}
+
+	at := time.Now()
+	stamp, err := fileSystemStamp(content)
+	if err != nil {
+		return "", err
+	}
+	s.contentStampsMu.Lock()
+	if s.contentStamps == nil {
+		s.contentStamps = map[string]*contentStampEntry{}
+	}
+	s.contentStamps[contentVersion] = &contentStampEntry{content: content, stamp: stamp, at: at}
+	s.contentStampsMu.Unlock()
+	return stamp, nil
+}
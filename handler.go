@@ -1,6 +1,10 @@
 package docsite
 
 import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
@@ -9,6 +13,9 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/sourcegraph/docsite/internal/search"
 )
 
 // versionPattern matches version strings like @5.2, @5.2.0, etc. and captures major and minor version numbers
@@ -90,7 +97,7 @@ func (s *Site) Handler() http.Handler {
 	}
 
 	// Serve search.
-	m.Handle(path.Join(basePath, "search"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	serveSearch := func(w http.ResponseWriter, r *http.Request, asJSON bool) {
 		if r.Method != "GET" && r.Method != "HEAD" {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
@@ -98,7 +105,37 @@ func (s *Site) Handler() http.Handler {
 
 		queryStr := r.URL.Query().Get("q")
 		contentVersion := r.URL.Query().Get("v")
-		result, err := s.Search(r.Context(), contentVersion, queryStr)
+		offset, limit, err := searchPageParams(r.URL.Query())
+		if err != nil {
+			w.Header().Set("Cache-Control", cacheMaxAge0)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Search multiple versions for "v=*" or "versions=a,b,c".
+		var contentVersions []string
+		if versionsStr := r.URL.Query().Get("versions"); contentVersion == "*" || versionsStr != "" {
+			if contentVersion == "*" {
+				versionsStr = "*"
+			}
+			contentVersions, err = s.ParseSearchVersions(versionsStr)
+			if err != nil {
+				w.Header().Set("Cache-Control", cacheMaxAge0)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		opt := search.Options{
+			Offset:  offset,
+			Limit:   limit,
+			Filters: searchFilterParams(r.URL.Query()),
+		}
+		var result *search.Result
+		start := time.Now()
+		if contentVersions != nil {
+			result, err = s.SearchVersions(r.Context(), contentVersions, queryStr, opt)
+		} else {
+			result, err = s.Search(r.Context(), contentVersion, queryStr, opt)
+		}
 		if err != nil {
 			w.Header().Set("Cache-Control", cacheMaxAge0)
 			http.Error(w, "search error: "+err.Error(), http.StatusInternalServerError)
@@ -107,19 +144,94 @@ func (s *Site) Handler() http.Handler {
 
 		var respData []byte
 		if r.Method == "GET" {
-			respData, err = s.renderSearchPage(contentVersion, queryStr, result)
+			// JSON responses are typically for search-as-you-type UIs, which would log a search for
+			// each keystroke, so they are only logged if requested (with "log=1").
+			if !asJSON || r.URL.Query().Get("log") == "1" {
+				s.logSearch(contentVersion, contentVersions, queryStr, result, time.Since(start))
+			}
+			clickURL := s.searchClickURL(basePath, r.URL.Query(), result)
+			if asJSON {
+				respData, err = json.Marshal(newSearchResponse(contentVersion, contentVersions, queryStr, result, clickURL))
+			} else {
+				respData, err = s.renderSearchPage(contentVersion, contentVersions, queryStr, r.URL.Query(), result, clickURL)
+			}
 			if err != nil {
 				w.Header().Set("Cache-Control", cacheMaxAge0)
 				http.Error(w, "template error: "+err.Error(), http.StatusInternalServerError)
 				return
 			}
 		}
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if asJSON {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
 		setCacheControl(w, r, cacheMaxAgeShort)
 		if r.Method == "GET" {
 			_, _ = w.Write(respData)
 		}
+	}
+	m.Handle(path.Join(basePath, "search"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The response is HTML or JSON depending on the Accept header, so caches must not serve one
+		// for the other.
+		w.Header().Set("Vary", "Accept")
+		serveSearch(w, r, strings.Contains(r.Header.Get("Accept"), "application/json"))
+	}))
+	m.Handle(path.Join(basePath, "search.json"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveSearch(w, r, true)
+	}))
+	m.Handle(path.Join(basePath, "search", "click"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.serveSearchClick(w, r, basePath)
 	}))
+	m.Handle(path.Join(basePath, "search", "complete"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		prefix := r.URL.Query().Get("q")
+		contentVersion := r.URL.Query().Get("v")
+		limit, err := intParam(r.URL.Query(), "limit", defaultSearchLimit, 1, maxSearchLimit)
+		if err != nil {
+			w.Header().Set("Cache-Control", cacheMaxAge0)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		completions, err := s.Complete(r.Context(), contentVersion, prefix, limit)
+		if err != nil {
+			w.Header().Set("Cache-Control", cacheMaxAge0)
+			http.Error(w, "search error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		respData, err := json.Marshal(newCompleteResponse(contentVersion, prefix, completions))
+		if err != nil {
+			w.Header().Set("Cache-Control", cacheMaxAge0)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		setCacheControl(w, r, cacheMaxAgeShort)
+		if r.Method == "GET" {
+			_, _ = w.Write(respData)
+		}
+	}))
+	robotsTxtHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		setCacheControl(w, r, cacheMaxAgeLong)
+		if r.Method == "GET" {
+			_, _ = w.Write(s.RobotsTxt())
+		}
+	})
+	m.Handle(path.Join(basePath, "robots.txt"), robotsTxtHandler)
+	if basePath != "/" {
+		// Crawlers only read robots.txt at the root of the host.
+		m.Handle("/robots.txt", robotsTxtHandler)
+	}
 
 	// Serve content.
 	m.Handle(basePath, http.StripPrefix(basePath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -160,6 +272,55 @@ func (s *Site) Handler() http.Handler {
 			r = requestShallowCopyWithURLPath(r, urlPath)
 		}
 
+		// Serve the sitemap of the content version, and the sitemap index that lists the sitemaps of
+		// all versions.
+		if r.URL.Path == sitemapFileName || (r.URL.Path == sitemapIndexFileName && contentVersion == "") {
+			var (
+				data []byte
+				err  error
+			)
+			if r.URL.Path == sitemapFileName {
+				data, err = s.Sitemap(r.Context(), contentVersion)
+			} else {
+				data, err = s.SitemapIndex()
+			}
+			if err != nil {
+				w.Header().Set("Cache-Control", cacheMaxAge0)
+				if err == errSitemapNoRoot || err == errSitemapNotIndexed || os.IsNotExist(err) {
+					http.Error(w, err.Error(), http.StatusNotFound)
+				} else {
+					http.Error(w, "sitemap error: "+err.Error(), http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+			setCacheControl(w, r, cacheMaxAgeLong)
+			if r.Method == "GET" {
+				_, _ = w.Write(data)
+			}
+			return
+		}
+
+		// Serve the Atom and RSS feeds of content directories.
+		if dir, format, ok := s.feedAtURLPath(r.URL.Path); ok {
+			data, err := s.Feed(r.Context(), contentVersion, dir, format)
+			if err != nil {
+				w.Header().Set("Cache-Control", cacheMaxAge0)
+				if err == errFeedNoRoot || os.IsNotExist(err) {
+					http.Error(w, err.Error(), http.StatusNotFound)
+				} else {
+					http.Error(w, "feed error: "+err.Error(), http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/"+format+"+xml; charset=utf-8")
+			setCacheControl(w, r, cacheMaxAgeLong)
+			if r.Method == "GET" {
+				_, _ = w.Write(data)
+			}
+			return
+		}
+
 		if IsContentAsset(r.URL.Path) {
 			// Serve non-Markdown content files (such as images) using http.FileServer.
 			content, err := s.Content.OpenVersion(r.Context(), contentVersion)
@@ -228,8 +389,24 @@ func (s *Site) Handler() http.Handler {
 
 				data.ContentPageNotFoundError = true
 			}
+
+			activePath := r.URL.Path
+			if data.Content != nil {
+				activePath = data.Content.Path
+			}
+			data.NavTree, err = s.NavTree(r.Context(), contentVersion, activePath)
+			if err != nil {
+				// Serve the page without navigation instead of failing it.
+				log.Printf("# Error building navigation tree for version %q: %s", contentVersion, err)
+				data.NavTree = nil
+			}
+			if data.Content != nil && data.NavTree != nil {
+				data.Content.setNavLinks(data.NavTree)
+			}
 		}
 
+		data.RobotsMeta = s.robotsMeta(contentVersion, data.Content)
+
 		var respData []byte
 		if r.Method == "GET" {
 			var err error
@@ -258,6 +435,37 @@ func (s *Site) Handler() http.Handler {
 	return m
 }
 
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 100
+)
+
+// searchPageParams returns the offset and limit of the requested page of search results from the
+// "offset" and "limit" URL query parameters.
+func searchPageParams(params url.Values) (offset, limit int, err error) {
+	if offset, err = intParam(params, "offset", 0, 0, math.MaxInt32); err != nil {
+		return 0, 0, err
+	}
+	if limit, err = intParam(params, "limit", defaultSearchLimit, 1, maxSearchLimit); err != nil {
+		return 0, 0, err
+	}
+	return offset, limit, nil
+}
+
+// intParam parses the named URL query parameter as an integer from min to max. If it is empty, it
+// returns defaultValue.
+func intParam(params url.Values, name string, defaultValue, min, max int) (int, error) {
+	str := params.Get(name)
+	if str == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(str)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("invalid %s parameter %q (must be an integer from %d to %d)", name, str, min, max)
+	}
+	return n, nil
+}
+
 func requestShallowCopyWithURLPath(r *http.Request, path string) *http.Request {
 	r2 := new(http.Request)
 	*r2 = *r
@@ -3,9 +3,11 @@ package docsite
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -40,6 +42,7 @@ func TestSite_Handler(t *testing.T) {
 				"index.md":                       "z [a/b](a/b/index.md)",
 				"a/b/index.md":                   "e",
 				"a/b/c.md":                       "d",
+				"h.md":                           "# Hello\n\n## World",
 				"a/b/img/f.gif":                  string(gifData),
 				"_resources/templates/root.html": `{{block "content" .}}empty{{end}}`,
 				"_resources/templates/document.html": `
@@ -262,8 +265,138 @@ func TestSite_Handler(t *testing.T) {
 		if want := `query "d":<a href="/a/b/c.md#">d</a>`; !strings.Contains(rr.Body.String(), want) {
 			t.Errorf("got body %q, want contains %q", rr.Body.String(), want)
 		}
+		if got, want := rr.Header().Get("Vary"), "Accept"; got != want {
+			t.Errorf("got Vary %q, want %q", got, want)
+		}
 	})
 	
+	t.Run("search JSON", func(t *testing.T) {
+		checkSearchJSONResponse := func(t *testing.T, rr *httptest.ResponseRecorder) {
+			t.Helper()
+			checkResponseHTTPOK(t, rr)
+			if got, want := rr.Header().Get("Content-Type"), "application/json; charset=utf-8"; got != want {
+				t.Errorf("got Content-Type %q, want %q", got, want)
+			}
+			want := `{"query":"d","contentVersion":"","total":1,"offset":0,"limit":10,"hasMore":false,"results":[{"id":"a/b/c.md","title":"","url":"/a/b/c","score":`
+			if !strings.HasPrefix(rr.Body.String(), want) {
+				t.Errorf("got body %q, want prefix %q", rr.Body.String(), want)
+			}
+			if want := `"sections":[{"id":"","idStack":[""],"title":"","titleStack":[""],"url":"/a/b/c","excerpts":["\u003cstrong\u003ed\u003c/strong\u003e"]}]}],"facets":{"categories":[],"tags":[],"dirs":[{"value":"a","count":1,"selected":false}]}}`; !strings.HasSuffix(rr.Body.String(), want) {
+				t.Errorf("got body %q, want suffix %q", rr.Body.String(), want)
+			}
+		}
+
+		t.Run("search.json", func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rr.Body = new(bytes.Buffer)
+			req, _ := http.NewRequest("GET", "/search.json?q=d", nil)
+			handler.ServeHTTP(rr, req)
+			checkSearchJSONResponse(t, rr)
+		})
+
+		t.Run("Accept header", func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rr.Body = new(bytes.Buffer)
+			req, _ := http.NewRequest("GET", "/search?q=d", nil)
+			req.Header.Set("Accept", "application/json")
+			handler.ServeHTTP(rr, req)
+			checkSearchJSONResponse(t, rr)
+			if got, want := rr.Header().Get("Vary"), "Accept"; got != want {
+				t.Errorf("got Vary %q, want %q", got, want)
+			}
+		})
+
+		t.Run("offset past end", func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rr.Body = new(bytes.Buffer)
+			req, _ := http.NewRequest("GET", "/search.json?q=d&offset=5&limit=1", nil)
+			handler.ServeHTTP(rr, req)
+			checkResponseHTTPOK(t, rr)
+			if want := `{"query":"d","contentVersion":"","total":1,"offset":5,"limit":1,"hasMore":false,"results":[],"facets":{"categories":[],"tags":[],"dirs":[{"value":"a","count":1,"selected":false}]}}`; rr.Body.String() != want {
+				t.Errorf("got body %q, want %q", rr.Body.String(), want)
+			}
+		})
+
+		t.Run("facet filter", func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rr.Body = new(bytes.Buffer)
+			req, _ := http.NewRequest("GET", "/search.json?q=d&dir=x", nil)
+			handler.ServeHTTP(rr, req)
+			checkResponseHTTPOK(t, rr)
+			if want := `{"query":"d","contentVersion":"","total":0,"offset":0,"limit":10,"hasMore":false,"results":[],"facets":{"categories":[],"tags":[],"dirs":[{"value":"a","count":1,"selected":false},{"value":"x","count":0,"selected":true}]}}`; rr.Body.String() != want {
+				t.Errorf("got body %q, want %q", rr.Body.String(), want)
+			}
+		})
+
+		t.Run("multiple versions", func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rr.Body = new(bytes.Buffer)
+			req, _ := http.NewRequest("GET", "/search.json?q=other&versions=,otherversion", nil)
+			handler.ServeHTTP(rr, req)
+			checkResponseHTTPOK(t, rr)
+			var resp struct {
+				Versions []string
+				Results  []struct {
+					URL      string
+					Versions []string
+				}
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"", "otherversion"}; !reflect.DeepEqual(resp.Versions, want) {
+				t.Errorf("got versions %q, want %q", resp.Versions, want)
+			}
+			if len(resp.Results) != 2 {
+				t.Fatalf("got %d results, want 2", len(resp.Results))
+			}
+			for _, result := range resp.Results {
+				if !strings.HasPrefix(result.URL, "/@otherversion/") || !reflect.DeepEqual(result.Versions, []string{"otherversion"}) {
+					t.Errorf("got result %+v, want result in otherversion", result)
+				}
+			}
+		})
+
+		t.Run("all versions not configured", func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/search.json?q=a&v=*", nil)
+			handler.ServeHTTP(rr, req)
+			checkResponseStatus(t, rr, http.StatusBadRequest)
+		})
+
+		t.Run("invalid limit", func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/search.json?q=d&limit=1000", nil)
+			handler.ServeHTTP(rr, req)
+			checkResponseStatus(t, rr, http.StatusBadRequest)
+		})
+	})
+
+	t.Run("search complete", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		rr.Body = new(bytes.Buffer)
+		req, _ := http.NewRequest("GET", "/search/complete?q=wor", nil)
+		handler.ServeHTTP(rr, req)
+		checkResponseHTTPOK(t, rr)
+		if got, want := rr.Header().Get("Content-Type"), "application/json; charset=utf-8"; got != want {
+			t.Errorf("got Content-Type %q, want %q", got, want)
+		}
+		if want := `{"query":"wor","contentVersion":"","completions":[{"title":"World","documentTitle":"Hello","url":"/h#world"}]}`; rr.Body.String() != want {
+			t.Errorf("got body %q, want %q", rr.Body.String(), want)
+		}
+
+		t.Run("other version", func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rr.Body = new(bytes.Buffer)
+			req, _ := http.NewRequest("GET", "/search/complete?q=wor&v=otherversion", nil)
+			handler.ServeHTTP(rr, req)
+			checkResponseHTTPOK(t, rr)
+			if want := `{"query":"wor","contentVersion":"otherversion","completions":[]}`; rr.Body.String() != want {
+				t.Errorf("got body %q, want %q", rr.Body.String(), want)
+			}
+		})
+	})
+
 	t.Run("version redirects", func(t *testing.T) {
 		t.Run("version 5.1 - no redirect", func(t *testing.T) {
 			rr := httptest.NewRecorder()
@@ -0,0 +1,126 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Token is a single word in a text and the term it is indexed and searched as.
+type Token struct {
+	Term       string // the analyzed term
+	Start, End int    // the byte offsets of the word in the text
+}
+
+// Analyze splits text into words and returns a token for each word. Words are sequences of
+// letters and digits. Each word is folded to lowercase without accents and stemmed (see Normalize
+// and Stem).
+//
+// The same analysis must be used for indexed text and for queries. Stop words are not removed,
+// because they are needed to match phrases. Callers that want to ignore them should use
+// IsStopWord.
+func Analyze(text string) []Token {
+	var tokens []Token
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start != -1 {
+			tokens = appendToken(tokens, text, start, i)
+			start = -1
+		}
+	}
+	if start != -1 {
+		tokens = appendToken(tokens, text, start, len(text))
+	}
+	return tokens
+}
+
+func appendToken(tokens []Token, text string, start, end int) []Token {
+	term := Stem(Normalize(text[start:end]))
+	if term == "" {
+		// The word consisted only of combining marks.
+		return tokens
+	}
+	return append(tokens, Token{Term: term, Start: start, End: end})
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+// Tokenize returns the terms of all words in text. See Analyze.
+func Tokenize(text string) []string {
+	tokens := Analyze(text)
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		terms[i] = token.Term
+	}
+	return terms
+}
+
+// Normalize returns the word in lowercase, with accents and other diacritics removed (so that
+// "Café" and "cafe" are the same term). It does not stem the word.
+func Normalize(word string) string {
+	var b strings.Builder
+	b.Grow(len(word))
+	for _, r := range word {
+		if unicode.Is(unicode.Mn, r) {
+			continue // combining mark (in decomposed text)
+		}
+		r = unicode.ToLower(r)
+		if s, ok := foldings[r]; ok {
+			b.WriteString(s)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Foldings returns the letters (such as "é") that Normalize replaces, and their replacements (such
+// as "e"), for implementations of Normalize in other languages.
+func Foldings() map[string]string {
+	m := make(map[string]string, len(foldings))
+	for r, s := range foldings {
+		m[string(r)] = s
+	}
+	return m
+}
+
+// IsStopWord reports whether the analyzed term is a common English word (such as "the") that
+// carries little meaning in a query.
+func IsStopWord(term string) bool {
+	_, ok := stopTerms[term]
+	return ok
+}
+
+// StopTerms returns the analyzed terms of the stop words, in sorted order.
+func StopTerms() []string {
+	terms := make([]string, 0, len(stopTerms))
+	for term := range stopTerms {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// stopWords are the English stop words.
+var stopWords = []string{
+	"a", "an", "and", "are", "as", "at", "be", "but", "by", "for", "if", "in", "into", "is", "it",
+	"no", "not", "of", "on", "or", "such", "that", "the", "their", "then", "there", "these",
+	"they", "this", "to", "was", "will", "with",
+}
+
+// stopTerms are the analyzed terms of the stop words.
+var stopTerms = func() map[string]struct{} {
+	terms := make(map[string]struct{}, len(stopWords))
+	for _, word := range stopWords {
+		terms[Stem(word)] = struct{}{}
+	}
+	return terms
+}()
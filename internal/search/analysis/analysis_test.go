@@ -0,0 +1,80 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := map[string][]string{
+		"":                  {},
+		"a":                 {"a"},
+		"Foo bar":           {"foo", "bar"},
+		"foo.bar-baz_qux":   {"foo", "bar", "baz", "qux"},
+		"[text](../a/b.md)": {"text", "a", "b", "md"},
+		"  x\ty\nz  ":       {"x", "y", "z"},
+		"Größe 42":          {"gross", "42"},
+		"\x9f\x92\xa1 a":    {"a"},
+		"Configuring the configured configuration": {"configur", "the", "configur", "configur"},
+		"Café café café":                          {"cafe", "cafe", "cafe"},
+		"go google algorithm":                      {"go", "googl", "algorithm"},
+	}
+	for text, want := range tests {
+		t.Run(text, func(t *testing.T) {
+			if got := Tokenize(text); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	got := Analyze("Ünïcode, running!")
+	want := []Token{
+		{Term: "unicod", Start: 0, End: 9},
+		{Term: "run", Start: 11, End: 18},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestIsStopWord(t *testing.T) {
+	tests := map[string]bool{
+		"the":  true,
+		"this": true,
+		"with": true,
+		"go":   false,
+		"then": true,
+		"site": false,
+	}
+	for word, want := range tests {
+		t.Run(word, func(t *testing.T) {
+			if got := IsStopWord(Stem(word)); got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCodeTokens(t *testing.T) {
+	tests := map[string][]Token{
+		"":                   nil,
+		"foo bar":            nil,
+		"--max-workers":      {{Term: "--max-workers", Start: 0, End: 13}},
+		"SRC_LOG_LEVEL=info": {{Term: "src_log_level", Start: 0, End: 13}},
+		"os.Getenv(x)":       {{Term: "os.getenv", Start: 0, End: 9}},
+		"run -v.":            {{Term: "-v", Start: 4, End: 6}},
+		"see foo-bar.":       {{Term: "foo-bar", Start: 4, End: 11}},
+		"_private ...":       nil,
+		"---":                nil,
+		"v1.2.3":             {{Term: "v1.2.3", Start: 0, End: 6}},
+	}
+	for text, want := range tests {
+		t.Run(text, func(t *testing.T) {
+			if got := CodeTokens(text); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package analysis
+
+import "strings"
+
+// CodeTokens returns a token for each identifier in code (such as "--max-workers", "SRC_LOG_LEVEL",
+// or "os.Getenv") that consists of words joined by "_", "-", or ".", or that begins with "-" (as
+// command-line flags do). Analyze splits identifiers into their words, so these tokens are in
+// addition to the tokens of the words.
+//
+// The term of an identifier is normalized (see Normalize) but not stemmed. It always contains one
+// of the joining characters, so it is distinct from the terms of words.
+func CodeTokens(text string) []Token {
+	var tokens []Token
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) || isCodeJoiner(r) {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start != -1 {
+			tokens = appendCodeToken(tokens, text, start, i)
+			start = -1
+		}
+	}
+	if start != -1 {
+		tokens = appendCodeToken(tokens, text, start, len(text))
+	}
+	return tokens
+}
+
+func appendCodeToken(tokens []Token, text string, start, end int) []Token {
+	// Omit joiners at the end (such as a period at the end of a sentence), and at the start (except
+	// for the dashes of flags).
+	for end > start && isCodeJoiner(rune(text[end-1])) {
+		end--
+	}
+	for start < end && (text[start] == '_' || text[start] == '.') {
+		start++
+	}
+	word := text[start:end]
+	if !strings.ContainsAny(word, "_-.") || strings.Trim(word, "_-.") == "" {
+		// A word without joiners is already a token (see Analyze).
+		return tokens
+	}
+	return append(tokens, Token{Term: Normalize(word), Start: start, End: end})
+}
+
+func isCodeJoiner(r rune) bool {
+	return r == '_' || r == '-' || r == '.'
+}
+
+// IsCodeTerm reports whether the term is the term of a code identifier (see CodeTokens).
+func IsCodeTerm(term string) bool {
+	return strings.ContainsAny(term, "_-.")
+}
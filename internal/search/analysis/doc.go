@@ -0,0 +1,2 @@
+// Package analysis splits text into the terms that are indexed and searched for.
+package analysis
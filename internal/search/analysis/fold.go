@@ -0,0 +1,37 @@
+package analysis
+
+// foldings maps lowercase letters with diacritics (and some ligatures) to their ASCII equivalents.
+var foldings = func() map[rune]string {
+	letters := map[string]string{
+		"a":  "àáâãäåāăąǎ",
+		"c":  "çćĉċč",
+		"d":  "ďđð",
+		"e":  "èéêëēĕėęě",
+		"g":  "ĝğġģ",
+		"h":  "ĥħ",
+		"i":  "ìíîïĩīĭįıǐ",
+		"j":  "ĵ",
+		"k":  "ķ",
+		"l":  "ĺļľŀł",
+		"n":  "ñńņňŉ",
+		"o":  "òóôõöøōŏőǒ",
+		"r":  "ŕŗř",
+		"s":  "śŝşšș",
+		"t":  "ţťŧț",
+		"u":  "ùúûüũūŭůűųǔ",
+		"w":  "ŵ",
+		"y":  "ýÿŷ",
+		"z":  "źżž",
+		"ae": "æ",
+		"oe": "œ",
+		"ss": "ß",
+		"th": "þ",
+	}
+	foldings := map[rune]string{}
+	for to, from := range letters {
+		for _, r := range from {
+			foldings[r] = to
+		}
+	}
+	return foldings
+}()
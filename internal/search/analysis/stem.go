@@ -0,0 +1,247 @@
+package analysis
+
+// Stem returns the stem of a lowercase English word, using the Porter stemming algorithm (see
+// https://tartarus.org/martin/PorterStemmer/). For example, "configure", "configured", and
+// "configuring" all have the stem "configur".
+//
+// Words with fewer than 3 letters and words that contain characters other than a-z are returned
+// unchanged.
+func Stem(word string) string {
+	if len(word) < 3 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+	s := stemmer{b: []byte(word)}
+	s.step1a()
+	s.step1b()
+	s.step1c()
+	s.replaceSuffix(step2Rules, 0)
+	s.replaceSuffix(step3Rules, 0)
+	s.step4()
+	s.step5()
+	return string(s.b)
+}
+
+type stemmer struct {
+	b []byte
+}
+
+// isConsonant reports whether b[i] is a consonant. The letter "y" is a consonant unless it
+// follows a consonant.
+func (s *stemmer) isConsonant(i int) bool {
+	switch s.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !s.isConsonant(i-1)
+	}
+	return true
+}
+
+// measure returns the number of vowel-consonant sequences in b[:n]. Every word has the form
+// [C](VC){m}[V], where C is a sequence of consonants and V is a sequence of vowels, and the
+// measure is m.
+func (s *stemmer) measure(n int) int {
+	var m, i int
+	for i < n && s.isConsonant(i) {
+		i++
+	}
+	for i < n {
+		for i < n && !s.isConsonant(i) {
+			i++
+		}
+		if i == n {
+			break
+		}
+		for i < n && s.isConsonant(i) {
+			i++
+		}
+		m++
+	}
+	return m
+}
+
+// hasVowel reports whether b[:n] contains a vowel.
+func (s *stemmer) hasVowel(n int) bool {
+	for i := 0; i < n; i++ {
+		if !s.isConsonant(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsWithDoubleConsonant reports whether b[:n] ends with two of the same consonant.
+func (s *stemmer) endsWithDoubleConsonant(n int) bool {
+	return n >= 2 && s.b[n-1] == s.b[n-2] && s.isConsonant(n-1)
+}
+
+// endsWithCVC reports whether b[:n] ends with consonant-vowel-consonant, where the last consonant
+// is not "w", "x", or "y" (as in "hop" but not "snow").
+func (s *stemmer) endsWithCVC(n int) bool {
+	if n < 3 || !s.isConsonant(n-3) || s.isConsonant(n-2) || !s.isConsonant(n-1) {
+		return false
+	}
+	switch s.b[n-1] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+// stemLen returns the length of the word without suffix, or -1 if the word does not end with
+// suffix.
+func (s *stemmer) stemLen(suffix string) int {
+	n := len(s.b) - len(suffix)
+	if n < 0 || string(s.b[n:]) != suffix {
+		return -1
+	}
+	return n
+}
+
+func (s *stemmer) setSuffix(n int, suffix string) {
+	s.b = append(s.b[:n], suffix...)
+}
+
+// step1a removes plurals.
+func (s *stemmer) step1a() {
+	switch {
+	case s.stemLen("sses") != -1:
+		s.b = s.b[:len(s.b)-2]
+	case s.stemLen("ies") != -1:
+		s.b = s.b[:len(s.b)-2]
+	case s.stemLen("ss") != -1:
+	case s.stemLen("s") != -1:
+		s.b = s.b[:len(s.b)-1]
+	}
+}
+
+// step1b removes -ed and -ing.
+func (s *stemmer) step1b() {
+	if n := s.stemLen("eed"); n != -1 {
+		if s.measure(n) > 0 {
+			s.b = s.b[:len(s.b)-1]
+		}
+		return
+	}
+
+	n := s.stemLen("ed")
+	if n == -1 {
+		n = s.stemLen("ing")
+	}
+	if n == -1 || !s.hasVowel(n) {
+		return
+	}
+	s.b = s.b[:n]
+	switch {
+	case s.stemLen("at") != -1, s.stemLen("bl") != -1, s.stemLen("iz") != -1:
+		s.b = append(s.b, 'e')
+	case s.endsWithDoubleConsonant(n):
+		if c := s.b[n-1]; c != 'l' && c != 's' && c != 'z' {
+			s.b = s.b[:n-1]
+		}
+	case s.measure(n) == 1 && s.endsWithCVC(n):
+		s.b = append(s.b, 'e')
+	}
+}
+
+// step1c replaces a final "y" with "i" if the word contains another vowel.
+func (s *stemmer) step1c() {
+	if n := s.stemLen("y"); n != -1 && s.hasVowel(n) {
+		s.b[n] = 'i'
+	}
+}
+
+// suffixRule replaces a suffix.
+type suffixRule struct {
+	suffix, replacement string
+}
+
+// replaceSuffix applies the first rule whose suffix the word ends with, if the measure of the rest
+// of the word is greater than minMeasure. Rules for longer suffixes must precede rules for their
+// own suffixes.
+func (s *stemmer) replaceSuffix(rules []suffixRule, minMeasure int) {
+	for _, rule := range rules {
+		if n := s.stemLen(rule.suffix); n != -1 {
+			if s.measure(n) > minMeasure {
+				s.setSuffix(n, rule.replacement)
+			}
+			return
+		}
+	}
+}
+
+// step2Rules map double suffixes to single ones.
+var step2Rules = []suffixRule{
+	{"ational", "ate"},
+	{"tional", "tion"},
+	{"enci", "ence"},
+	{"anci", "ance"},
+	{"izer", "ize"},
+	{"bli", "ble"},
+	{"alli", "al"},
+	{"entli", "ent"},
+	{"eli", "e"},
+	{"ousli", "ous"},
+	{"ization", "ize"},
+	{"ation", "ate"},
+	{"ator", "ate"},
+	{"alism", "al"},
+	{"iveness", "ive"},
+	{"fulness", "ful"},
+	{"ousness", "ous"},
+	{"aliti", "al"},
+	{"iviti", "ive"},
+	{"biliti", "ble"},
+	{"logi", "log"},
+}
+
+// step3Rules remove or simplify -ic-, -full, -ness, etc.
+var step3Rules = []suffixRule{
+	{"icate", "ic"},
+	{"ative", ""},
+	{"alize", "al"},
+	{"iciti", "ic"},
+	{"ical", "ic"},
+	{"ful", ""},
+	{"ness", ""},
+}
+
+// step4Suffixes are removed in step 4.
+var step4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment", "ent", "ion", "ou",
+	"ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+// step4 removes -ant, -ence, etc. from words with a measure greater than 1.
+func (s *stemmer) step4() {
+	for _, suffix := range step4Suffixes {
+		n := s.stemLen(suffix)
+		if n == -1 {
+			continue
+		}
+		if suffix == "ion" && (n == 0 || (s.b[n-1] != 's' && s.b[n-1] != 't')) {
+			continue
+		}
+		if s.measure(n) > 1 {
+			s.b = s.b[:n]
+		}
+		return
+	}
+}
+
+// step5 removes a final "-e" and reduces a final "-ll" to "-l" in longer words.
+func (s *stemmer) step5() {
+	if n := s.stemLen("e"); n != -1 {
+		if m := s.measure(n); m > 1 || (m == 1 && !s.endsWithCVC(n)) {
+			s.b = s.b[:n]
+		}
+	}
+	if n := len(s.b); s.b[n-1] == 'l' && s.endsWithDoubleConsonant(n) && s.measure(n) > 1 {
+		s.b = s.b[:n-1]
+	}
+}
@@ -0,0 +1,44 @@
+package analysis
+
+import "testing"
+
+func TestStem(t *testing.T) {
+	tests := map[string]string{
+		"a":               "a",
+		"go":              "go",
+		"caresses":        "caress",
+		"ponies":          "poni",
+		"cats":            "cat",
+		"agreed":          "agre",
+		"plastered":       "plaster",
+		"motoring":        "motor",
+		"sing":            "sing",
+		"conflated":       "conflat",
+		"hopping":         "hop",
+		"falling":         "fall",
+		"filing":          "file",
+		"happy":           "happi",
+		"relational":      "relat",
+		"conditional":     "condit",
+		"generalizations": "gener",
+		"hopefulness":     "hope",
+		"adjustment":      "adjust",
+		"adoption":        "adopt",
+		"controllable":    "control",
+		"probate":         "probat",
+		"rate":            "rate",
+		"configure":       "configur",
+		"configuring":     "configur",
+		"configuration":   "configur",
+		"google":          "googl",
+		"größe":           "größe",
+		"v2":              "v2",
+	}
+	for word, want := range tests {
+		t.Run(word, func(t *testing.T) {
+			if got := Stem(word); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
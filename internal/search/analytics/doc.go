@@ -0,0 +1,2 @@
+// Package analytics records searches and clicks on search results, and summarizes them.
+package analytics
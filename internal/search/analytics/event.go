@@ -0,0 +1,34 @@
+package analytics
+
+import "time"
+
+// EventType is the type of an Event.
+type EventType string
+
+const (
+	EventSearch EventType = "search" // a search
+	EventClick  EventType = "click"  // a click on a search result
+)
+
+// Event is a search or a click on a search result.
+type Event struct {
+	Time           time.Time `json:"time"`
+	Type           EventType `json:"type"`
+	Query          string    `json:"query"`
+	ContentVersion string    `json:"contentVersion"`
+	Versions       []string  `json:"versions,omitempty"` // the content versions searched (if multiple)
+
+	// Search events only
+	Offset    int     `json:"offset,omitempty"`    // the number of results skipped (for later pages of results)
+	Results   int     `json:"results"`             // the total number of document results
+	LatencyMS float64 `json:"latencyMs,omitempty"` // the time taken to search, in milliseconds
+
+	// Click events only
+	URL  string `json:"url,omitempty"`  // the URL of the clicked result
+	Rank int    `json:"rank,omitempty"` // the position of the clicked result (1 for the first result)
+}
+
+// Sink records events.
+type Sink interface {
+	Log(Event) error
+}
@@ -0,0 +1,164 @@
+package analytics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultMaxSize  = 10 << 20 // the default maximum size of a file (in bytes)
+	DefaultMaxFiles = 5        // the default number of files kept
+)
+
+// FileSink is a Sink that appends events as lines of JSON to a file. When the file would exceed
+// MaxSize bytes, it is rotated: it is renamed to Path + ".1" (and an existing Path + ".1" to Path +
+// ".2", and so on), keeping at most MaxFiles files (including the current file).
+type FileSink struct {
+	Path     string
+	MaxSize  int64 // the maximum size of a file (DefaultMaxSize if 0)
+	MaxFiles int   // the maximum number of files (DefaultMaxFiles if 0)
+
+	mu   sync.Mutex
+	f    *os.File // the current file (opened when the first event is logged)
+	size int64    // the size of the current file
+}
+
+// Log implements Sink.
+func (s *FileSink) Log(e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	if s.size > 0 && s.size+int64(len(line)) > s.maxSize() {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.f.Write(line)
+	s.size += int64(n)
+	return errors.WithMessage(err, "write search analytics event")
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.WithMessage(err, "open search analytics file")
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.f, s.size = f, fi.Size()
+	return nil
+}
+
+// rotate renames the current file (and the older files) and opens a new file.
+func (s *FileSink) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return err
+	}
+	s.f = nil
+
+	maxFiles := s.MaxFiles
+	if maxFiles == 0 {
+		maxFiles = DefaultMaxFiles
+	}
+	if err := os.Remove(rotatedPath(s.Path, maxFiles-1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := maxFiles - 2; i >= 0; i-- {
+		if err := os.Rename(rotatedPath(s.Path, i), rotatedPath(s.Path, i+1)); err != nil && !os.IsNotExist(err) {
+			return errors.WithMessage(err, "rotate search analytics file")
+		}
+	}
+	return s.open()
+}
+
+func (s *FileSink) maxSize() int64 {
+	if s.MaxSize == 0 {
+		return DefaultMaxSize
+	}
+	return s.MaxSize
+}
+
+// Close closes the current file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// rotatedPath returns the path of the i'th most recently rotated file (or path itself if i is 0).
+func rotatedPath(path string, i int) string {
+	if i == 0 {
+		return path
+	}
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+// ReadFiles reads the events in the file at path and in the files rotated from it (see FileSink),
+// oldest first. Lines that are not valid events (such as a line that was partially written) are
+// skipped.
+func ReadFiles(path string) ([]Event, error) {
+	var paths []string
+	for i := 0; ; i++ {
+		p := rotatedPath(path, i)
+		if _, err := os.Stat(p); err != nil {
+			if os.IsNotExist(err) && i > 0 {
+				break
+			}
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+
+	var events []Event
+	for i := len(paths) - 1; i >= 0; i-- {
+		f, err := os.Open(paths[i])
+		if err != nil {
+			return nil, err
+		}
+		fileEvents, err := readEvents(f)
+		f.Close()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "read %s", paths[i])
+		}
+		events = append(events, fileEvents...)
+	}
+	return events, nil
+}
+
+func readEvents(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
@@ -0,0 +1,70 @@
+package analytics
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "search.jsonl")
+	sink := &FileSink{Path: path, MaxSize: 100, MaxFiles: 3}
+	defer sink.Close()
+
+	var queries []string
+	for _, query := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		if err := sink.Log(Event{Type: EventSearch, Query: query}); err != nil {
+			t.Fatal(err)
+		}
+		queries = append(queries, query)
+	}
+
+	for i, p := range []string{path, path + ".1", path + ".2"} {
+		fi, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("file %d: %s", i, err)
+		}
+		if fi.Size() > sink.MaxSize {
+			t.Errorf("file %d: got size %d, want at most %d", i, fi.Size(), sink.MaxSize)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("got error %v for file 3, want not exist (exceeds MaxFiles)", err)
+	}
+
+	events, err := ReadFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Query)
+	}
+	// The oldest events were removed with the oldest file.
+	if want := queries[len(queries)-len(got):]; len(got) == 0 || !reflect.DeepEqual(got, want) {
+		t.Errorf("got queries %q, want %q", got, want)
+	}
+}
+
+func TestReadFiles(t *testing.T) {
+	t.Run("invalid lines", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "search.jsonl")
+		if err := os.WriteFile(path, []byte(`{"type":"search","query":"a"}`+"\n"+`{"type":"sea`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		events, err := ReadFiles(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || events[0].Query != "a" {
+			t.Errorf("got events %+v, want 1 event", events)
+		}
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		if _, err := ReadFiles(filepath.Join(t.TempDir(), "search.jsonl")); !os.IsNotExist(err) {
+			t.Errorf("got error %v, want not exist", err)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package analytics
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Stats summarizes events.
+type Stats struct {
+	Searches           int           // the number of searches (not counting later pages of results)
+	ZeroResultSearches int           // the number of searches with no results
+	Clicks             int           // the number of clicks on search results
+	MeanLatency        time.Duration // the mean time taken to search
+	TopQueries         []QueryStats  // the most frequent queries (most frequent first)
+	ZeroResultQueries  []QueryStats  // the most frequent queries with no results (most frequent first)
+}
+
+// QueryStats summarizes the events for a query.
+type QueryStats struct {
+	Query    string // the query (normalized by NormalizeQuery)
+	Searches int    // the number of searches for the query
+	Clicks   int    // the number of clicks on results of the query
+}
+
+// NormalizeQuery returns the query in lowercase with runs of whitespace replaced by a single space,
+// so that queries that differ only in case and whitespace are counted together.
+func NormalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// Summarize returns the stats of the events, with at most n of the top queries and zero-result
+// queries (or all of them if n is 0).
+func Summarize(events []Event, n int) *Stats {
+	var (
+		stats      Stats
+		latency    time.Duration
+		byQuery    = map[string]*QueryStats{}
+		zeroResult = map[string]*QueryStats{}
+	)
+	get := func(m map[string]*QueryStats, query string) *QueryStats {
+		qs, ok := m[query]
+		if !ok {
+			qs = &QueryStats{Query: query}
+			m[query] = qs
+		}
+		return qs
+	}
+	for _, e := range events {
+		query := NormalizeQuery(e.Query)
+		switch e.Type {
+		case EventSearch:
+			if e.Offset > 0 {
+				continue
+			}
+			stats.Searches++
+			latency += time.Duration(e.LatencyMS * float64(time.Millisecond))
+			get(byQuery, query).Searches++
+			if e.Results == 0 {
+				stats.ZeroResultSearches++
+				get(zeroResult, query).Searches++
+			}
+		case EventClick:
+			stats.Clicks++
+			get(byQuery, query).Clicks++
+		}
+	}
+	if stats.Searches > 0 {
+		stats.MeanLatency = latency / time.Duration(stats.Searches)
+	}
+	stats.TopQueries = topQueries(byQuery, n)
+	stats.ZeroResultQueries = topQueries(zeroResult, n)
+	return &stats
+}
+
+// topQueries returns the n queries with the most searches (or all of them if n is 0), breaking ties
+// by clicks and then by query.
+func topQueries(m map[string]*QueryStats, n int) []QueryStats {
+	queries := make([]QueryStats, 0, len(m))
+	for _, qs := range m {
+		queries = append(queries, *qs)
+	}
+	sort.Slice(queries, func(i, j int) bool {
+		a, b := queries[i], queries[j]
+		if a.Searches != b.Searches {
+			return a.Searches > b.Searches
+		}
+		if a.Clicks != b.Clicks {
+			return a.Clicks > b.Clicks
+		}
+		return a.Query < b.Query
+	})
+	if n > 0 && len(queries) > n {
+		queries = queries[:n]
+	}
+	return queries
+}
@@ -0,0 +1,62 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	events := []Event{
+		{Type: EventSearch, Query: "SSO", Results: 2, LatencyMS: 10},
+		{Type: EventSearch, Query: " sso ", Results: 2, LatencyMS: 20},
+		{Type: EventSearch, Query: "sso", Offset: 10, Results: 2, LatencyMS: 30}, // later page (not counted)
+		{Type: EventClick, Query: "sso", URL: "/a", Rank: 1},
+		{Type: EventSearch, Query: "saml", Results: 1, LatencyMS: 30},
+		{Type: EventSearch, Query: "authentcation", LatencyMS: 20},
+		{Type: EventSearch, Query: "foo", LatencyMS: 20},
+		{Type: EventSearch, Query: "foo", LatencyMS: 20},
+	}
+
+	tests := map[string]struct {
+		n    int
+		want *Stats
+	}{
+		"all": {
+			want: &Stats{
+				Searches:           6,
+				ZeroResultSearches: 3,
+				Clicks:             1,
+				MeanLatency:        20 * time.Millisecond,
+				TopQueries: []QueryStats{
+					{Query: "sso", Searches: 2, Clicks: 1},
+					{Query: "foo", Searches: 2},
+					{Query: "authentcation", Searches: 1},
+					{Query: "saml", Searches: 1},
+				},
+				ZeroResultQueries: []QueryStats{
+					{Query: "foo", Searches: 2},
+					{Query: "authentcation", Searches: 1},
+				},
+			},
+		},
+		"n": {
+			n: 1,
+			want: &Stats{
+				Searches:           6,
+				ZeroResultSearches: 3,
+				Clicks:             1,
+				MeanLatency:        20 * time.Millisecond,
+				TopQueries:         []QueryStats{{Query: "sso", Searches: 2, Clicks: 1}},
+				ZeroResultQueries:  []QueryStats{{Query: "foo", Searches: 2}},
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := Summarize(events, test.n); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package search
+
+import "sort"
+
+// Filters restricts search results to documents with the selected facet values. Documents must
+// match at least one selected value of each facet that has any selected values.
+type Filters struct {
+	Categories []string // the selected categories
+	Tags       []string // the selected tags
+	Dirs       []string // the selected top-level directories
+}
+
+// Facets are the numbers of document results with each category, tag, and top-level directory.
+//
+// The counts for each facet are of the document results that match the filters of the other
+// facets (but not its own filter), so that selecting another value of a facet adds to the results.
+type Facets struct {
+	Categories []FacetValue
+	Tags       []FacetValue
+	Dirs       []FacetValue
+}
+
+// FacetValue is the number of document results with a facet value.
+type FacetValue struct {
+	Value    string // the category, tag, or directory
+	Count    int    // the number of document results
+	Selected bool   // whether the value is selected in the filters
+}
+
+// filterFacets returns the document results that match the filters, and the facets of all of the
+// document results.
+func filterFacets(drs []DocumentResult, filters Filters) ([]DocumentResult, Facets) {
+	categories := map[string]int{}
+	tags := map[string]int{}
+	dirs := map[string]int{}
+
+	var filtered []DocumentResult
+	for _, dr := range drs {
+		categoryOK := matchFilter(filters.Categories, dr.Category)
+		tagOK := matchFilter(filters.Tags, dr.Tags...)
+		dirOK := matchFilter(filters.Dirs, dr.Dir)
+		if tagOK && dirOK && dr.Category != "" {
+			categories[dr.Category]++
+		}
+		if categoryOK && dirOK {
+			seen := map[string]struct{}{}
+			for _, tag := range dr.Tags {
+				if _, ok := seen[tag]; !ok {
+					seen[tag] = struct{}{}
+					tags[tag]++
+				}
+			}
+		}
+		if categoryOK && tagOK && dr.Dir != "" {
+			dirs[dr.Dir]++
+		}
+		if categoryOK && tagOK && dirOK {
+			filtered = append(filtered, dr)
+		}
+	}
+
+	facets := Facets{
+		Categories: facetValues(categories, filters.Categories),
+		Tags:       facetValues(tags, filters.Tags),
+		Dirs:       facetValues(dirs, filters.Dirs),
+	}
+	return filtered, facets
+}
+
+// matchFilter reports whether any of the values is selected, or whether no values are selected.
+func matchFilter(selected []string, values ...string) bool {
+	if len(selected) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if contains(selected, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// facetValues returns the facet values in order of decreasing count (and then by value). Selected
+// values are included even if they have no results.
+func facetValues(counts map[string]int, selected []string) []FacetValue {
+	for _, s := range selected {
+		counts[s] += 0
+	}
+	values := make([]FacetValue, 0, len(counts))
+	for value, count := range counts {
+		values = append(values, FacetValue{Value: value, Count: count, Selected: contains(selected, value)})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Count > values[j].Count || (values[i].Count == values[j].Count && values[i].Value < values[j].Value)
+	})
+	return values
+}
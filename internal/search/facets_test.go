@@ -0,0 +1,85 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/docsite/internal/search/index"
+)
+
+func TestFilterFacets(t *testing.T) {
+	doc := func(id, category, dir string, tags ...string) DocumentResult {
+		return DocumentResult{DocumentResult: index.DocumentResult{Document: index.Document{ID: index.DocID(id), Category: category, Dir: dir, Tags: tags}}}
+	}
+	drs := []DocumentResult{
+		doc("a", "guides", "admin", "api", "auth"),
+		doc("b", "guides", "dev", "api"),
+		doc("c", "reference", "admin"),
+		doc("d", "", ""),
+	}
+
+	tests := map[string]struct {
+		filters Filters
+		ids     []index.DocID
+		facets  Facets
+	}{
+		"no filters": {
+			ids: []index.DocID{"a", "b", "c", "d"},
+			facets: Facets{
+				Categories: []FacetValue{{Value: "guides", Count: 2}, {Value: "reference", Count: 1}},
+				Tags:       []FacetValue{{Value: "api", Count: 2}, {Value: "auth", Count: 1}},
+				Dirs:       []FacetValue{{Value: "admin", Count: 2}, {Value: "dev", Count: 1}},
+			},
+		},
+		"category": {
+			filters: Filters{Categories: []string{"guides"}},
+			ids:     []index.DocID{"a", "b"},
+			facets: Facets{
+				Categories: []FacetValue{{Value: "guides", Count: 2, Selected: true}, {Value: "reference", Count: 1}},
+				Tags:       []FacetValue{{Value: "api", Count: 2}, {Value: "auth", Count: 1}},
+				Dirs:       []FacetValue{{Value: "admin", Count: 1}, {Value: "dev", Count: 1}},
+			},
+		},
+		"multiple values of a facet": {
+			filters: Filters{Dirs: []string{"dev", "admin"}},
+			ids:     []index.DocID{"a", "b", "c"},
+			facets: Facets{
+				Categories: []FacetValue{{Value: "guides", Count: 2}, {Value: "reference", Count: 1}},
+				Tags:       []FacetValue{{Value: "api", Count: 2}, {Value: "auth", Count: 1}},
+				Dirs:       []FacetValue{{Value: "admin", Count: 2, Selected: true}, {Value: "dev", Count: 1, Selected: true}},
+			},
+		},
+		"multiple facets": {
+			filters: Filters{Tags: []string{"api"}, Dirs: []string{"admin"}},
+			ids:     []index.DocID{"a"},
+			facets: Facets{
+				Categories: []FacetValue{{Value: "guides", Count: 1}},
+				Tags:       []FacetValue{{Value: "api", Count: 1, Selected: true}, {Value: "auth", Count: 1}},
+				Dirs:       []FacetValue{{Value: "admin", Count: 1, Selected: true}, {Value: "dev", Count: 1}},
+			},
+		},
+		"unknown value": {
+			filters: Filters{Tags: []string{"x"}},
+			facets: Facets{
+				Categories: []FacetValue{},
+				Tags:       []FacetValue{{Value: "api", Count: 2}, {Value: "auth", Count: 1}, {Value: "x", Selected: true}},
+				Dirs:       []FacetValue{},
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			filtered, facets := filterFacets(drs, test.filters)
+			var ids []index.DocID
+			for _, dr := range filtered {
+				ids = append(ids, dr.ID)
+			}
+			if !reflect.DeepEqual(ids, test.ids) {
+				t.Errorf("got %v, want %v", ids, test.ids)
+			}
+			if !reflect.DeepEqual(facets, test.facets) {
+				t.Errorf("got facets %+v, want %+v", facets, test.facets)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package index
+
+import (
+	"strings"
+
+	"github.com/sourcegraph/docsite/internal/search/analysis"
+	"github.com/sourcegraph/docsite/internal/search/query"
+	"github.com/sourcegraph/docsite/markdown"
+)
+
+// BundleFormatVersion is the version of the Bundle format. It must be incremented whenever the
+// format changes incompatibly, so that client-side scripts can reject bundles they don't support.
+const BundleFormatVersion = 1
+
+// Bundle is the index in a self-contained form for client-side search (such as in a static export
+// of the site with no server). It contains the text of every section, so that excerpts can be
+// shown, and a compact map of terms to the sections that contain them.
+//
+// The words of a query are looked up with analysis.Normalize (which is simple to implement in a
+// client, given Foldings) and then Words, so clients don't need to implement stemming.
+type Bundle struct {
+	FormatVersion  int              `json:"formatVersion"`
+	ContentVersion string           `json:"contentVersion"`
+	K1             float64          `json:"k1"` // term frequency saturation (see query.ScoreParams)
+	Documents      []BundleDocument `json:"documents"`
+
+	// Terms maps each term to a flattened list of (document, section, weight) triples, where
+	// document and section are indexes in Documents and the document's Sections, and weight is the
+	// number of occurrences of the term in the section, weighted by the boost of each field.
+	Terms map[string][]float64 `json:"terms"`
+
+	// Words maps each normalized word in the documents to its term, if they differ.
+	Words map[string]string `json:"words"`
+
+	// StopTerms are the terms that are ignored in queries (see analysis.IsStopWord).
+	StopTerms []string `json:"stopTerms"`
+
+	// Foldings maps letters to their replacements in normalized words (see analysis.Foldings).
+	Foldings map[string]string `json:"foldings"`
+}
+
+// BundleDocument is a document in a Bundle.
+type BundleDocument struct {
+	ID       DocID           `json:"id"`
+	Title    string          `json:"title"`
+	URL      string          `json:"url"`
+	Tags     []string        `json:"tags,omitempty"`
+	Category string          `json:"category,omitempty"`
+	Dir      string          `json:"dir,omitempty"`
+	Boost    float64         `json:"boost,omitempty"` // the factor to multiply the document's scores by (1 if 0)
+	Sections []BundleSection `json:"sections"`
+}
+
+// BundleSection is a section of a document in a Bundle. The first section of every document
+// consists of the content before the first (non-title) heading, as in the index.
+type BundleSection struct {
+	ID         string   `json:"id"`
+	IDStack    []string `json:"idStack"`
+	Title      string   `json:"title"`
+	TitleStack []string `json:"titleStack"`
+	Text       string   `json:"text"` // the plain text of the section's blocks, one per line
+}
+
+// Bundle returns the index as a Bundle. Occurrences of terms are weighted by the field boosts of
+// the score parameters.
+func (i *Index) Bundle(params query.ScoreParams) *Bundle {
+	b := &Bundle{
+		FormatVersion: BundleFormatVersion,
+		K1:            params.K1,
+		Documents:     make([]BundleDocument, 0, len(i.ids)),
+		Terms:         make(map[string][]float64, len(i.postings)),
+		Words:         map[string]string{},
+		StopTerms:     analysis.StopTerms(),
+		Foldings:      analysis.Foldings(),
+	}
+	addWords := func(text string) {
+		for _, token := range analysis.Analyze(text) {
+			if word := analysis.Normalize(text[token.Start:token.End]); word != token.Term {
+				b.Words[word] = token.Term
+			}
+		}
+	}
+
+	nums := make(map[docNum]int, len(i.ids)) // bundle document numbers (without removed documents)
+	for n, d := range i.docs {
+		if d == nil {
+			continue
+		}
+		nums[docNum(n)] = len(b.Documents)
+		sections := bundleSections(d.Data)
+		b.Documents = append(b.Documents, BundleDocument{
+			ID:       d.ID,
+			Title:    d.Title,
+			URL:      d.URL,
+			Tags:     d.Tags,
+			Category: d.Category,
+			Dir:      d.Dir,
+			Boost:    d.Boost,
+			Sections: sections,
+		})
+
+		addWords(d.Title)
+		addWords(d.URL)
+		addWords(d.Category)
+		for _, tag := range d.Tags {
+			addWords(tag)
+		}
+		for _, keyword := range d.Keywords {
+			addWords(keyword)
+		}
+		for _, s := range sections {
+			addWords(s.Title)
+			addWords(s.Text)
+		}
+	}
+
+	for term, ps := range i.postings {
+		var triples []float64
+		for _, p := range ps {
+			weights := make([]float64, len(i.docs[p.doc].sections))
+			for _, pos := range p.positions {
+				weights[pos.section] += params.Boosts[pos.field]
+			}
+			for section, weight := range weights {
+				if weight > 0 {
+					triples = append(triples, float64(nums[p.doc]), float64(section), weight)
+				}
+			}
+		}
+		if len(triples) > 0 {
+			b.Terms[term] = triples
+		}
+	}
+	return b
+}
+
+// bundleSections returns the sections of the document's Markdown text (the same sections as
+// analyze).
+func bundleSections(source []byte) []BundleSection {
+	textSections := markdown.ExtractText(source)
+	sections := make([]BundleSection, len(textSections))
+	for j, ts := range textSections {
+		// The stacks start with an empty entry for the document (as in search.SectionResult).
+		s := BundleSection{ID: ts.ID, Title: ts.Title, IDStack: []string{""}, TitleStack: []string{""}}
+		for _, p := range markdown.SectionPath(textSections, j) {
+			s.IDStack = append(s.IDStack, p.ID)
+			s.TitleStack = append(s.TitleStack, p.Title)
+		}
+		texts := make([]string, len(ts.Blocks))
+		for k, block := range ts.Blocks {
+			texts[k] = block.Text
+		}
+		s.Text = strings.Join(texts, "\n")
+		sections[j] = s
+	}
+	return sections
+}
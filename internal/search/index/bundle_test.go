@@ -0,0 +1,73 @@
+package index
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/docsite/internal/search/query"
+)
+
+func TestIndex_Bundle(t *testing.T) {
+	docs := []Document{
+		{ID: "a", URL: "/a", Title: "A", Data: []byte("# A\n\nIntro *text*.\n\n## Running\n\nRun it.\n\n```\ncode\nlines\n```\n\n### Deeper\n\n## Other\n\nx")},
+		{ID: "b", URL: "/b", Data: []byte("removed")},
+		{ID: "c", URL: "/c", Tags: []string{"t"}, Data: []byte("running")},
+	}
+	idx, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range docs {
+		if err := idx.Add(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx.remove(idx.ids["b"])
+
+	params := query.DefaultScoreParams()
+	b := idx.Bundle(params)
+
+	wantDocs := []BundleDocument{
+		{
+			ID: "a", Title: "A", URL: "/a",
+			Sections: []BundleSection{
+				{ID: "", IDStack: []string{"", ""}, Title: "A", TitleStack: []string{"", "A"}, Text: "Intro text."},
+				{ID: "running", IDStack: []string{"", "", "running"}, Title: "Running", TitleStack: []string{"", "A", "Running"}, Text: "Run it.\ncode\nlines"},
+				{ID: "deeper", IDStack: []string{"", "", "running", "deeper"}, Title: "Deeper", TitleStack: []string{"", "A", "Running", "Deeper"}},
+				{ID: "other", IDStack: []string{"", "", "other"}, Title: "Other", TitleStack: []string{"", "A", "Other"}, Text: "x"},
+			},
+		},
+		{
+			ID: "c", URL: "/c", Tags: []string{"t"},
+			Sections: []BundleSection{{ID: "", IDStack: []string{""}, TitleStack: []string{""}, Text: "running"}},
+		},
+	}
+	if !reflect.DeepEqual(b.Documents, wantDocs) {
+		t.Errorf("got documents %+v, want %+v", b.Documents, wantDocs)
+	}
+
+	wantTerms := map[string][]float64{
+		"run": {
+			0, 1, params.Boosts[query.FieldHeading] + params.Boosts[query.FieldBody],
+			1, 0, params.Boosts[query.FieldBody],
+		},
+		"code": {0, 1, params.Boosts[query.FieldCode]},
+		"t":    {1, 0, params.Boosts[query.FieldTags]},
+	}
+	for term, want := range wantTerms {
+		if got := b.Terms[term]; !reflect.DeepEqual(got, want) {
+			t.Errorf("term %q: got %v, want %v", term, got, want)
+		}
+	}
+	if _, ok := b.Terms["remov"]; ok {
+		t.Error("got term of removed document")
+	}
+
+	if got, want := b.Words["running"], "run"; got != want {
+		t.Errorf("got word %q for %q, want %q", got, "running", want)
+	}
+	if _, ok := b.Words["code"]; ok {
+		t.Error("got word that is the same as its term")
+	}
+}
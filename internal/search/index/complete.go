@@ -0,0 +1,135 @@
+package index
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sourcegraph/docsite/internal/search/analysis"
+)
+
+// Completion is a document title or section heading that completes a prefix.
+type Completion struct {
+	ID            DocID  // the document ID
+	Title         string // the document title or section heading
+	DocumentTitle string // the document title
+	URL           string // the URL of the document or section (with a "#" fragment for a section)
+}
+
+// completionKey is an entry in the sorted dictionary of titles and headings. There is an entry for
+// each word of a title, so that prefixes of any word in a title complete it.
+type completionKey struct {
+	key     string // the normalized title, starting at the word
+	doc     docNum
+	section int
+}
+
+// Complete returns up to n document titles and section headings that contain a word beginning
+// with the prefix (or that begin with the prefix, if it has multiple words). Titles that begin with
+// the prefix are first, then document titles, then shorter titles.
+func (i *Index) Complete(prefix string, n int) []Completion {
+	prefix = normalizeTitle(prefix)
+	if strings.TrimSpace(prefix) == "" {
+		return nil
+	}
+
+	type candidate struct {
+		Completion
+		isStart   bool // whether the title begins with the prefix
+		isSection bool
+	}
+	var candidates []candidate
+	seen := map[completionKey]struct{}{}
+	keys := i.completionKeys()
+	for j := sort.Search(len(keys), func(j int) bool { return keys[j].key >= prefix }); j < len(keys) && strings.HasPrefix(keys[j].key, prefix); j++ {
+		k := keys[j]
+		if _, ok := seen[completionKey{doc: k.doc, section: k.section}]; ok {
+			continue
+		}
+		seen[completionKey{doc: k.doc, section: k.section}] = struct{}{}
+		d := i.docs[k.doc]
+		title := d.sectionTitle(k.section)
+		url := d.URL
+		if id := d.sections[k.section].id; id != "" {
+			url += "#" + id
+		}
+		candidates = append(candidates, candidate{
+			Completion: Completion{
+				ID:            d.ID,
+				Title:         title,
+				DocumentTitle: d.sectionTitle(0),
+				URL:           url,
+			},
+			isStart:   strings.HasPrefix(normalizeTitle(title), prefix),
+			isSection: k.section != 0,
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.isStart != b.isStart {
+			return a.isStart
+		}
+		if a.isSection != b.isSection {
+			return !a.isSection
+		}
+		if len(a.Title) != len(b.Title) {
+			return len(a.Title) < len(b.Title)
+		}
+		return a.URL < b.URL
+	})
+
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	completions := make([]Completion, len(candidates))
+	for j, c := range candidates {
+		completions[j] = c.Completion
+	}
+	return completions
+}
+
+// completionKeys returns the sorted dictionary of titles and headings of all documents.
+func (i *Index) completionKeys() []completionKey {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.completions == nil {
+		i.completions = []completionKey{}
+		for n, d := range i.docs {
+			if d == nil {
+				continue
+			}
+			for section := range d.sections {
+				title := d.sectionTitle(section)
+				for _, token := range analysis.Analyze(title) {
+					i.completions = append(i.completions, completionKey{
+						key:     normalizeTitle(title[token.Start:]),
+						doc:     docNum(n),
+						section: section,
+					})
+				}
+			}
+		}
+		sort.Slice(i.completions, func(j, k int) bool {
+			a, b := i.completions[j], i.completions[k]
+			return a.key < b.key || (a.key == b.key && (a.doc < b.doc || (a.doc == b.doc && a.section < b.section)))
+		})
+	}
+	return i.completions
+}
+
+// sectionTitle returns the title of the section. The first section's title is the document title.
+func (d *document) sectionTitle(section int) string {
+	if section == 0 && d.Title != "" {
+		return d.Title
+	}
+	return d.sections[section].title
+}
+
+// normalizeTitle returns the title in lowercase without diacritics and with runs of spaces
+// collapsed, for prefix matching.
+func normalizeTitle(title string) string {
+	s := strings.Join(strings.Fields(analysis.Normalize(title)), " ")
+	if s != "" && strings.TrimRight(title, " \t") != title {
+		s += " " // a trailing space means the last word is complete
+	}
+	return s
+}
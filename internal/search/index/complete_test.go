@@ -0,0 +1,62 @@
+package index
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestIndex_Complete(t *testing.T) {
+	docs := []Document{
+		{ID: "a", URL: "/a", Title: "Configuration", Data: []byte("# Configuration\n\n## Advanced configuration\n\n## Café settings")},
+		{ID: "b", URL: "/b", Title: "Getting started", Data: []byte("# Getting started\n\n## Configure the site")},
+		{ID: "c", URL: "/c", Data: []byte("no title")},
+	}
+	idx, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range docs {
+		if err := idx.Add(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string][]string{
+		"":              nil,
+		"  ":            nil,
+		"conf":          {"/a", "/b#configure-the-site", "/a#advanced-configuration"},
+		"CONFIGURATION": {"/a", "/a#advanced-configuration"},
+		"configure ":    {"/b#configure-the-site"},
+		"getting  st":   {"/b"},
+		"started":       {"/b"},
+		"cafe":          {"/a#café-settings"},
+		"title":         nil,
+		"zzz":           nil,
+	}
+	for prefix, want := range tests {
+		t.Run(prefix, func(t *testing.T) {
+			var got []string
+			for _, c := range idx.Complete(prefix, 10) {
+				got = append(got, c.URL)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+
+	t.Run("limit", func(t *testing.T) {
+		if got := idx.Complete("conf", 1); len(got) != 1 {
+			t.Errorf("got %d completions, want 1", len(got))
+		}
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		got := idx.Complete("advanced", 10)
+		want := []Completion{{ID: "a", Title: "Advanced configuration", DocumentTitle: "Configuration", URL: "/a#advanced-configuration"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package index
+
+import (
+	"encoding/gob"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/docsite/internal/search/query"
+)
+
+// FormatVersion is the version of the encoded index format. It must be incremented whenever the
+// format (or the analysis of text into terms) changes, so that indexes encoded by older versions
+// are detected and rebuilt.
+const FormatVersion = 5
+
+// ErrFormatVersion is returned by Decode if the encoded index has a different format version.
+var ErrFormatVersion = errors.New("search index format version is not supported")
+
+// encodedIndex is the encoded form of an index. Removed documents are omitted, and the remaining
+// documents are renumbered.
+type encodedIndex struct {
+	FormatVersion int
+	Docs          []encodedDocument
+	Postings      map[string][]encodedPosting
+	FieldLens     [query.NumFields]int
+	Words         map[string]string
+}
+
+type encodedDocument struct {
+	Document  Document
+	Sections  []encodedSection
+	FieldLens [query.NumFields]int
+	Terms     []string
+}
+
+type encodedSection struct {
+	ID, Title string
+}
+
+type encodedPosting struct {
+	Doc       int
+	Positions []encodedPosition
+}
+
+type encodedPosition struct {
+	Field           query.Field
+	Section, Offset int
+}
+
+// Encode writes the index to w. Use Decode to read it.
+func (i *Index) Encode(w io.Writer) error {
+	e := encodedIndex{
+		FormatVersion: FormatVersion,
+		Docs:          make([]encodedDocument, 0, len(i.ids)),
+		Postings:      make(map[string][]encodedPosting, len(i.postings)),
+		FieldLens:     i.fieldLens,
+		Words:         i.words,
+	}
+	nums := make(map[docNum]int, len(i.ids)) // new document numbers (without removed documents)
+	for n, d := range i.docs {
+		if d == nil {
+			continue
+		}
+		nums[docNum(n)] = len(e.Docs)
+		sections := make([]encodedSection, len(d.sections))
+		for j, s := range d.sections {
+			sections[j] = encodedSection{ID: s.id, Title: s.title}
+		}
+		e.Docs = append(e.Docs, encodedDocument{
+			Document:  d.Document,
+			Sections:  sections,
+			FieldLens: d.fieldLens,
+			Terms:     d.terms,
+		})
+	}
+	for term, ps := range i.postings {
+		eps := make([]encodedPosting, len(ps))
+		for j, p := range ps {
+			positions := make([]encodedPosition, len(p.positions))
+			for k, pos := range p.positions {
+				positions[k] = encodedPosition{Field: pos.field, Section: pos.section, Offset: pos.offset}
+			}
+			eps[j] = encodedPosting{Doc: nums[p.doc], Positions: positions}
+		}
+		e.Postings[term] = eps
+	}
+	return gob.NewEncoder(w).Encode(e)
+}
+
+// Decode reads an index that was written by Encode. If the index was encoded in a different format
+// version, it returns ErrFormatVersion.
+func Decode(r io.Reader) (*Index, error) {
+	var e encodedIndex
+	if err := gob.NewDecoder(r).Decode(&e); err != nil {
+		return nil, errors.WithMessage(err, "decode search index")
+	}
+	if e.FormatVersion != FormatVersion {
+		return nil, errors.WithMessagef(ErrFormatVersion, "got version %d, want %d", e.FormatVersion, FormatVersion)
+	}
+
+	i, err := New()
+	if err != nil {
+		return nil, err
+	}
+	i.docs = make([]*document, len(e.Docs))
+	for n, ed := range e.Docs {
+		sections := make([]section, len(ed.Sections))
+		for j, s := range ed.Sections {
+			sections[j] = section{id: s.ID, title: s.Title}
+		}
+		i.docs[n] = &document{
+			Document:  ed.Document,
+			sections:  sections,
+			fieldLens: ed.FieldLens,
+			terms:     ed.Terms,
+		}
+		i.ids[ed.Document.ID] = docNum(n)
+	}
+	for term, eps := range e.Postings {
+		ps := make([]posting, len(eps))
+		for j, ep := range eps {
+			p := posting{doc: docNum(ep.Doc), positions: make([]position, len(ep.Positions))}
+			for k, pos := range ep.Positions {
+				p.positions[k] = position{field: pos.Field, section: pos.Section, offset: pos.Offset}
+				p.freqs[pos.Field]++
+			}
+			ps[j] = p
+		}
+		i.postings[term] = ps
+	}
+	i.fieldLens = e.FieldLens
+	if e.Words != nil {
+		i.words = e.Words
+	}
+	return i, nil
+}
@@ -0,0 +1,69 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/docsite/internal/search/query"
+)
+
+func TestIndex_Encode(t *testing.T) {
+	idx, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := []Document{
+		{ID: "a", URL: "/a", Title: "Alpha", Data: []byte("# Alpha\n\nfoo bar\n\n## Beta\n\nbaz"), Tags: []string{"x"}, Category: "c"},
+		{ID: "b", URL: "/b", Data: []byte("bar baz")},
+		{ID: "a", URL: "/a", Title: "Alpha", Data: []byte("# Alpha\n\nfoo qux\n\n## Beta\n\nbaz")}, // replaces a
+	}
+	for _, doc := range docs {
+		if err := idx.Add(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := idx.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, queryStr := range []string{"foo", "bar", "baz", "qux", "title:alpha", `"foo qux"`, "ba*", "-foo"} {
+		q := query.Parse(queryStr)
+		want, err := idx.Search(q, query.DefaultScoreParams())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := decoded.Search(q, query.DefaultScoreParams())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", queryStr, got, want)
+		}
+	}
+	if got, want := decoded.Complete("be", 10), idx.Complete("be", 10); !reflect.DeepEqual(got, want) {
+		t.Errorf("got completions %+v, want %+v", got, want)
+	}
+	if got, want := decoded.Suggest("quux", 10), idx.Suggest("quux", 10); !reflect.DeepEqual(got, want) {
+		t.Errorf("got suggestions %q, want %q", got, want)
+	}
+
+	t.Run("other format version", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(encodedIndex{FormatVersion: FormatVersion + 1}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Decode(&buf); !errors.Is(err, ErrFormatVersion) {
+			t.Errorf("got error %v, want ErrFormatVersion", err)
+		}
+	})
+}
@@ -2,6 +2,12 @@ package index
 
 import (
 	"context"
+	"sort"
+	"sync"
+
+	"github.com/sourcegraph/docsite/internal/search/analysis"
+	"github.com/sourcegraph/docsite/internal/search/query"
+	"github.com/sourcegraph/docsite/markdown"
 )
 
 // DocID is a unique identifier of a document in an index.
@@ -9,27 +15,247 @@ type DocID string
 
 // Document is a document to be indexed.
 type Document struct {
-	ID    DocID  // the document ID
-	Title string // the document title
-	URL   string // the document URL
-	Data  []byte // the text content
+	ID       DocID    // the document ID
+	Title    string   // the document title
+	URL      string   // the document URL
+	Path     string   // the URL path relative to the site base and version (such as "admin/install")
+	Data     []byte   // the text content
+	Tags     []string // the document tags (from its metadata)
+	Category string   // the document category (from its metadata)
+	Dir      string   // the top-level directory of the document (such as "admin"), or empty
+	Keywords []string // additional words and phrases to match the document (from its metadata)
+	Boost    float64  // the factor to multiply the document's scores by (1 if 0)
 }
 
-// Index is a search index.
+// Index is a search index. It is an inverted index that maps each term to the documents (and the
+// positions within those documents) where the term occurs.
+//
+// Add must not be called concurrently with other methods. Search may be called concurrently.
 type Index struct {
-	index map[DocID]Document
+	docs      []*document          // all documents (removed documents are nil)
+	ids       map[DocID]docNum     // the document number of each document ID
+	postings  map[string][]posting // postings list of each term, ordered by document number
+	fieldLens [query.NumFields]int // the total number of terms in each field of all documents
+	words     map[string]string    // the (shortest) word that each term was analyzed from
+
+	mu          sync.Mutex
+	terms       []string        // sorted terms (nil if it must be recomputed)
+	completions []completionKey // sorted dictionary of titles and headings (nil if it must be recomputed)
+}
+
+// docNum is the position of a document in (Index).docs.
+type docNum int
+
+// document is an indexed document.
+type document struct {
+	Document
+	sections  []section            // sections of the document, in order
+	fieldLens [query.NumFields]int // the number of terms in each field
+	terms     []string             // distinct terms in the document (to remove its postings)
+}
+
+// section is a part of a document starting at a heading. The first section of every document
+// consists of the content before the first (non-title) heading.
+type section struct {
+	id    string // the URL fragment (without "#") of the section, or empty for the first section
+	title string // the heading text
+}
+
+// posting records the occurrences of a term in a single document.
+type posting struct {
+	doc       docNum
+	positions []position
+	freqs     [query.NumFields]int // the number of positions in each field
+}
+
+// position is the location of a single occurrence of a term.
+//
+// The title, path, tags, category, and keywords fields are each tokenized separately, so their offsets start
+// at 0. The other fields are parts of the document text and share its offsets.
+type position struct {
+	field   query.Field
+	section int // the index of the section in the document's sections
+	offset  int // the token offset in the field's text
 }
 
 // New returns a new index.
 func New() (*Index, error) {
-	return &Index{}, nil
+	return &Index{
+		ids:      map[DocID]docNum{},
+		postings: map[string][]posting{},
+		words:    map[string]string{},
+	}, nil
 }
 
-// Add adds a document to the index.
+// Add adds a document to the index. If a document with the same ID already exists in the index, it
+// is replaced.
 func (i *Index) Add(ctx context.Context, doc Document) error {
-	if i.index == nil {
-		i.index = map[DocID]Document{}
+	if n, ok := i.ids[doc.ID]; ok {
+		i.remove(n)
+	}
+
+	d := analyze(doc)
+
+	n := docNum(len(i.docs))
+	i.docs = append(i.docs, &d.document)
+	i.ids[doc.ID] = n
+	for _, term := range d.terms {
+		p := posting{doc: n, positions: d.positions[term]}
+		for _, pos := range p.positions {
+			p.freqs[pos.field]++
+		}
+		i.postings[term] = append(i.postings[term], p)
+		if word, ok := i.words[term]; !ok || shorterWord(d.words[term], word) {
+			i.words[term] = d.words[term]
+		}
+	}
+	for f, l := range d.fieldLens {
+		i.fieldLens[f] += l
 	}
-	i.index[doc.ID] = doc
+	i.terms = nil
+	i.completions = nil
 	return nil
 }
+
+// Document returns the document with the ID, if it is in the index.
+func (i *Index) Document(id DocID) (Document, bool) {
+	n, ok := i.ids[id]
+	if !ok {
+		return Document{}, false
+	}
+	return i.docs[n].Document, true
+}
+
+// remove removes the document from the index.
+func (i *Index) remove(n docNum) {
+	d := i.docs[n]
+	for _, term := range d.terms {
+		ps := i.postings[term]
+		if j := sort.Search(len(ps), func(j int) bool { return ps[j].doc >= n }); j < len(ps) && ps[j].doc == n {
+			ps = append(ps[:j:j], ps[j+1:]...)
+		}
+		if len(ps) > 0 {
+			i.postings[term] = ps
+		} else {
+			delete(i.postings, term)
+			delete(i.words, term)
+		}
+	}
+	for f, l := range d.fieldLens {
+		i.fieldLens[f] -= l
+	}
+	delete(i.ids, d.ID)
+	i.docs[n] = nil
+	i.terms = nil
+	i.completions = nil
+}
+
+// sortedTerms returns all terms in the index in sorted order.
+func (i *Index) sortedTerms() []string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.terms == nil {
+		i.terms = make([]string, 0, len(i.postings))
+		for term := range i.postings {
+			i.terms = append(i.terms, term)
+		}
+		sort.Strings(i.terms)
+	}
+	return i.terms
+}
+
+// shorterWord reports whether word a should be preferred over word b to represent a term.
+func shorterWord(a, b string) bool {
+	return len(a) < len(b) || (len(a) == len(b) && a < b)
+}
+
+// analyzedDocument is a document and the positions of its terms.
+type analyzedDocument struct {
+	document
+	positions map[string][]position
+	words     map[string]string // the (shortest) word that each term was analyzed from
+}
+
+// analyze splits the document's Markdown text into sections and fields (using its plain text, see
+// markdown.ExtractText) and records the positions of all of its terms.
+func analyze(doc Document) *analyzedDocument {
+	d := &analyzedDocument{
+		document:  document{Document: doc, sections: []section{{}}},
+		positions: map[string][]position{},
+		words:     map[string]string{},
+	}
+	addTerm := func(field query.Field, term, word string, offset int) {
+		if _, seen := d.positions[term]; !seen {
+			d.terms = append(d.terms, term)
+		}
+		if w, ok := d.words[term]; !ok || shorterWord(word, w) {
+			d.words[term] = word
+		}
+		d.positions[term] = append(d.positions[term], position{
+			field:   field,
+			section: len(d.sections) - 1,
+			offset:  offset,
+		})
+	}
+	addText := func(field query.Field, text string, offset int) int {
+		for _, token := range analysis.Analyze(text) {
+			addTerm(field, token.Term, analysis.Normalize(text[token.Start:token.End]), offset)
+			d.fieldLens[field]++
+			offset++
+		}
+		return offset
+	}
+	// addCode is like addText for the code field, but it also adds the terms of identifiers in the
+	// code (such as "--max-workers", see analysis.CodeTokens), each at the offset of its first word.
+	addCode := func(text string, offset int) int {
+		codeTokens := analysis.CodeTokens(text)
+		for _, token := range analysis.Analyze(text) {
+			for len(codeTokens) > 0 && codeTokens[0].Start <= token.Start {
+				addTerm(query.FieldCode, codeTokens[0].Term, codeTokens[0].Term, offset)
+				codeTokens = codeTokens[1:]
+			}
+			addTerm(query.FieldCode, token.Term, analysis.Normalize(text[token.Start:token.End]), offset)
+			d.fieldLens[query.FieldCode]++
+			offset++
+		}
+		return offset
+	}
+
+	addText(query.FieldTitle, doc.Title, 0)
+	addText(query.FieldPath, doc.Path, 0)
+	addText(query.FieldCategory, doc.Category, 0)
+	var tagOffset int
+	for _, tag := range doc.Tags {
+		// Leave a gap between tags so that phrases do not match across tags.
+		tagOffset = addText(query.FieldTags, tag, tagOffset) + 1
+	}
+	var keywordOffset int
+	for _, keyword := range doc.Keywords {
+		keywordOffset = addText(query.FieldKeywords, keyword, keywordOffset) + 1
+	}
+
+	var offset int
+	for j, ts := range markdown.ExtractText(doc.Data) {
+		if j > 0 {
+			d.sections = append(d.sections, section{id: ts.ID})
+		}
+		d.sections[j].title = ts.Title
+		offset = addText(query.FieldHeading, ts.Title, offset)
+		for _, block := range ts.Blocks {
+			if block.Code {
+				offset = addCode(block.Text, offset)
+				continue
+			}
+			// Code spans (such as `--max-workers`) are code in the body text.
+			var start int
+			for _, span := range block.CodeSpans {
+				offset = addText(query.FieldBody, block.Text[start:span[0]], offset)
+				offset = addCode(block.Text[span[0]:span[1]], offset)
+				start = span[1]
+			}
+			offset = addText(query.FieldBody, block.Text[start:], offset)
+		}
+	}
+	sort.Strings(d.terms)
+	return d
+}
@@ -0,0 +1,233 @@
+package index
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/sourcegraph/docsite/internal/search/query"
+)
+
+func TestIndex_Search(t *testing.T) {
+	docs := []Document{
+		{ID: "a", URL: "/a", Path: "a", Title: "Alpha", Data: []byte("# Alpha\nfoo bar"), Category: "guides"},
+		{ID: "b", URL: "/b", Path: "b", Data: []byte("bar baz"), Tags: []string{"api", "admin tools"}},
+		{ID: "c", URL: "/dir/c-foo", Path: "dir/c-foo", Data: []byte("qux"), Tags: []string{"tools"}},
+		{ID: "d", URL: "/d", Path: "d", Data: []byte("Set `--max-workers`.\n\n```\nSRC_LOG_LEVEL=debug\n```")},
+		{ID: "e", URL: "/e", Path: "e", Data: []byte("The max workers setting.")},
+	}
+	idx, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range docs {
+		if err := idx.Add(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string][]DocID{
+		"foo":       {"a", "c"},
+		"bar":       {"a", "b"},
+		"ba":        nil,
+		"ba*":       {"a", "b"},
+		"alpha":     {"a"},
+		"qux foo":   {"c"},
+		"foo.bar":   {"a"},
+		"zzz":       nil,
+		"ar":        nil,
+		"FOO":       {"a", "c"},
+		"dir":       {"c"},
+		"c":         {"c"},
+		"-":         nil,
+		"baz alpha": nil,
+
+		"baz OR alpha":      {"a", "b"},
+		"baz OR alpha bar":  {"a", "b"},
+		"qux OR baz OR zzz": {"b", "c"},
+		"bar -baz":          {"a"},
+		"bar -ba":           {"a", "b"},
+		"bar -ba*":          nil,
+		`bar -"bar baz"`:    {"a"},
+		"-bar":              nil,
+		`"foo bar"`:         {"a"},
+		`"bar foo"`:         nil,
+		`"fo bar"`:          nil,
+		"fo bar":            nil,
+		"fo* bar":           {"a"},
+		"the bar":           {"a", "b"},
+		"title:alpha":       {"a"},
+		"title:foo":         nil,
+		"path:dir":          {"c"},
+		"path:qux":          nil,
+		"tag:tools":         {"b", "c"},
+		`tag:"admin tools"`: {"b"},
+		`tag:"api admin"`:   nil,
+		"category:guides":   {"a"},
+		"category:api":      nil,
+		"zzz:foo":           nil,
+		"-tag:api tools":    {"c"},
+
+		"--max-workers":      {"d", "e"},
+		"code:--max-workers": {"d"},
+		"max-workers":        {"d", "e"},
+		"src_log_level":      {"d"},
+		"SRC_LOG_LEVEL":      {"d"},
+		"src_log":            {"d"},
+		"code:debug":         {"d"},
+		"code:setting":       nil,
+	}
+	for queryStr, want := range tests {
+		t.Run(queryStr, func(t *testing.T) {
+			result, err := idx.Search(query.Parse(queryStr), query.DefaultScoreParams())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []DocID
+			for _, dr := range result.DocumentResults {
+				got = append(got, dr.ID)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+			if result.Total != len(want) {
+				t.Errorf("got total %d, want %d", result.Total, len(want))
+			}
+		})
+	}
+}
+
+func TestIndex_Add(t *testing.T) {
+	t.Run("replace", func(t *testing.T) {
+		idx, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := context.Background()
+		if err := idx.Add(ctx, Document{ID: "a", URL: "/a", Data: []byte("old")}); err != nil {
+			t.Fatal(err)
+		}
+		if err := idx.Add(ctx, Document{ID: "a", URL: "/a", Data: []byte("new")}); err != nil {
+			t.Fatal(err)
+		}
+		for queryStr, want := range map[string]int{"old": 0, "new": 1} {
+			result, err := idx.Search(query.Parse(queryStr), query.DefaultScoreParams())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Total != want {
+				t.Errorf("%s: got %d results, want %d", queryStr, result.Total, want)
+			}
+		}
+	})
+
+	t.Run("sections", func(t *testing.T) {
+		idx, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := "# Title\n\nx\n\n## Section 1\n\ny\n\n### Section 2\n\n- x\n- z"
+		if err := idx.Add(context.Background(), Document{ID: "a", Data: []byte(data)}); err != nil {
+			t.Fatal(err)
+		}
+		d := idx.docs[idx.ids["a"]]
+		if want := []section{{title: "Title"}, {id: "section-1", title: "Section 1"}, {id: "section-2", title: "Section 2"}}; !reflect.DeepEqual(d.sections, want) {
+			t.Errorf("got sections %+v, want %+v", d.sections, want)
+		}
+		wantPositions := map[string][]position{
+			"x":       {{field: query.FieldBody, section: 0, offset: 1}, {field: query.FieldBody, section: 2, offset: 7}},
+			"y":       {{field: query.FieldBody, section: 1, offset: 4}},
+			"section": {{field: query.FieldHeading, section: 1, offset: 2}, {field: query.FieldHeading, section: 2, offset: 5}},
+		}
+		for term, want := range wantPositions {
+			if got := idx.postings[term][0].positions; !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: got positions %+v, want %+v", term, got, want)
+			}
+		}
+	})
+}
+
+func TestIndex_Search_ranking(t *testing.T) {
+	tests := map[string]struct {
+		docs  []Document
+		query string
+		want  []DocID
+	}{
+		"title before body": {
+			docs: []Document{
+				{ID: "body", URL: "/1", Data: []byte("foo")},
+				{ID: "title", URL: "/2", Title: "foo", Data: []byte("# foo")},
+			},
+			query: "foo",
+			want:  []DocID{"title", "body"},
+		},
+		"body before code": {
+			docs: []Document{
+				{ID: "code", URL: "/1", Data: []byte("```\nfoo\n```")},
+				{ID: "body", URL: "/2", Data: []byte("foo")},
+			},
+			query: "foo",
+			want:  []DocID{"body", "code"},
+		},
+		"rare term before common term": {
+			docs: []Document{
+				{ID: "common", URL: "/1", Data: []byte("foo")},
+				{ID: "rare", URL: "/2", Data: []byte("bar")},
+				{ID: "other", URL: "/3", Data: []byte("foo")},
+			},
+			query: "foo OR bar",
+			want:  []DocID{"rare", "common", "other"},
+		},
+		"short field before long field": {
+			docs: []Document{
+				{ID: "long", URL: "/1", Data: []byte("foo a b c d e f g h i j")},
+				{ID: "short", URL: "/2", Data: []byte("foo a")},
+			},
+			query: "foo",
+			want:  []DocID{"short", "long"},
+		},
+		"keywords": {
+			docs: []Document{
+				{ID: "body", URL: "/1", Data: []byte("install foo")},
+				{ID: "keywords", URL: "/2", Data: []byte("foo"), Keywords: []string{"install", "setup"}},
+			},
+			query: "install foo",
+			want:  []DocID{"keywords", "body"},
+		},
+		"boost": {
+			docs: []Document{
+				{ID: "title", URL: "/1", Title: "foo", Data: []byte("# foo")},
+				{ID: "boosted", URL: "/2", Data: []byte("foo"), Boost: 10},
+				{ID: "demoted", URL: "/3", Title: "foo", Data: []byte("# foo"), Boost: 0.1},
+			},
+			query: "foo",
+			want:  []DocID{"boosted", "title", "demoted"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			idx, err := New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, doc := range test.docs {
+				if err := idx.Add(context.Background(), doc); err != nil {
+					t.Fatal(err)
+				}
+			}
+			result, err := idx.Search(query.Parse(test.query), query.DefaultScoreParams())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []DocID
+			for _, dr := range result.DocumentResults {
+				got = append(got, dr.ID)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
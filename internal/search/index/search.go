@@ -2,6 +2,7 @@ package index
 
 import (
 	"sort"
+	"strings"
 
 	"github.com/sourcegraph/docsite/internal/search/query"
 )
@@ -18,16 +19,33 @@ type DocumentResult struct {
 	Score float64
 }
 
-// Search performs a search against the index.
-func (i *Index) Search(query query.Query) (*Result, error) {
-	var documentResults []DocumentResult
-	for _, doc := range i.index {
-		if query.Match(doc.URL, doc.Data) {
-			documentResults = append(documentResults, DocumentResult{
-				Document: doc,
-				Score:    query.Score(doc.URL, doc.Data),
-			})
+// Search performs a search against the index. Only documents that contain at least one of the
+// query's (non-excluded) terms are considered, and they are scored using the corpus statistics of
+// the index.
+func (i *Index) Search(q query.Query, params query.ScoreParams) (*Result, error) {
+	s := &searcher{Index: i, terms: i.sortedTerms(), expansions: map[query.Term]*expansion{}}
+
+	candidates := map[docNum]struct{}{}
+	for _, term := range q.Terms() {
+		for n := range s.expand(term).docs {
+			candidates[n] = struct{}{}
+		}
+	}
+
+	documentResults := make([]DocumentResult, 0, len(candidates))
+	for n := range candidates {
+		stats := docStats{searcher: s, doc: n}
+		if !q.Match(stats) {
+			continue
+		}
+		score := q.Score(stats, params)
+		if boost := i.docs[n].Boost; boost != 0 {
+			score *= boost
 		}
+		documentResults = append(documentResults, DocumentResult{
+			Document: i.docs[n].Document,
+			Score:    score,
+		})
 	}
 	sort.Slice(documentResults, func(i, j int) bool {
 		return documentResults[i].Score > documentResults[j].Score || (documentResults[i].Score == documentResults[j].Score && documentResults[i].ID < documentResults[j].ID)
@@ -39,3 +57,106 @@ func (i *Index) Search(query query.Query) (*Result, error) {
 	}
 	return result, nil
 }
+
+// searcher holds the state of a single search.
+type searcher struct {
+	*Index
+	terms      []string                  // sorted terms of the index
+	expansions map[query.Term]*expansion // the expansion of each query term seen so far
+}
+
+// expansion is the set of indexed terms matched by a query term.
+type expansion struct {
+	terms []string            // the indexed terms that match the query term
+	docs  map[docNum]struct{} // the documents that contain any of the terms
+}
+
+// expand returns the expansion of the query term.
+func (s *searcher) expand(queryTerm query.Term) *expansion {
+	if e, ok := s.expansions[queryTerm]; ok {
+		return e
+	}
+	e := &expansion{docs: map[docNum]struct{}{}}
+	if queryTerm.Prefix {
+		e.terms = withPrefix(s.terms, queryTerm.Text)
+	} else if _, ok := s.postings[queryTerm.Text]; ok {
+		e.terms = []string{queryTerm.Text}
+	}
+	for _, term := range e.terms {
+		for _, p := range s.postings[term] {
+			e.docs[p.doc] = struct{}{}
+		}
+	}
+	s.expansions[queryTerm] = e
+	return e
+}
+
+// posting returns the posting of the term for the document, or nil if the document does not
+// contain the term.
+func (i *Index) posting(term string, n docNum) *posting {
+	ps := i.postings[term]
+	j := sort.Search(len(ps), func(j int) bool { return ps[j].doc >= n })
+	if j < len(ps) && ps[j].doc == n {
+		return &ps[j]
+	}
+	return nil
+}
+
+// docStats implements query.Stats and query.Doc for a document in the index.
+type docStats struct {
+	*searcher
+	doc docNum
+}
+
+var (
+	_ query.Stats = docStats{}
+	_ query.Doc   = docStats{}
+)
+
+func (s docStats) NumDocs() int { return len(s.ids) }
+
+func (s docStats) DocFreq(term query.Term) int { return len(s.expand(term).docs) }
+
+func (s docStats) TermFreq(field query.Field, term query.Term) int {
+	var freq int
+	for _, t := range s.expand(term).terms {
+		if p := s.posting(t, s.doc); p != nil {
+			freq += p.freqs[field]
+		}
+	}
+	return freq
+}
+
+func (s docStats) FieldLen(field query.Field) int { return s.docs[s.doc].fieldLens[field] }
+
+func (s docStats) AvgFieldLen(field query.Field) float64 {
+	if len(s.ids) == 0 {
+		return 0
+	}
+	return float64(s.fieldLens[field]) / float64(len(s.ids))
+}
+
+func (s docStats) Positions(field query.Field, term query.Term) []int {
+	var offsets []int
+	for _, t := range s.expand(term).terms {
+		if p := s.posting(t, s.doc); p != nil && p.freqs[field] > 0 {
+			for _, pos := range p.positions {
+				if pos.field == field {
+					offsets = append(offsets, pos.offset)
+				}
+			}
+		}
+	}
+	sort.Ints(offsets)
+	return offsets
+}
+
+// withPrefix returns the subslice of the sorted terms that begin with prefix.
+func withPrefix(terms []string, prefix string) []string {
+	start := sort.SearchStrings(terms, prefix)
+	end := start
+	for end < len(terms) && strings.HasPrefix(terms[end], prefix) {
+		end++
+	}
+	return terms[start:end]
+}
@@ -0,0 +1,111 @@
+package index
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/sourcegraph/docsite/internal/search/analysis"
+)
+
+// suggestMinLength is the minimum length (in letters) of a term to suggest similar terms for.
+const suggestMinLength = 3
+
+// Suggest returns up to n words from the index that are spelled similarly to the analyzed term
+// (which is typically misspelled and not in the index), most similar first and then most frequent
+// first.
+//
+// Terms within an edit distance of 1 (for terms of up to 4 letters) or 2 are similar. Each edit is
+// the insertion, deletion, or substitution of a letter, or the transposition of 2 adjacent
+// letters. Terms shorter than suggestMinLength letters (which are similar to too many terms) have
+// no suggestions, and stop words are never suggested.
+func (i *Index) Suggest(term string, n int) []string {
+	length := utf8.RuneCountInString(term)
+	if length < suggestMinLength {
+		return nil
+	}
+	maxDistance := 2
+	if length <= 4 {
+		maxDistance = 1
+	}
+
+	type candidate struct {
+		term     string
+		distance int
+		docFreq  int
+	}
+	var candidates []candidate
+	for _, t := range i.sortedTerms() {
+		if t == term || analysis.IsStopWord(t) {
+			continue
+		}
+		if d := editDistance(term, t, maxDistance); d <= maxDistance {
+			candidates = append(candidates, candidate{term: t, distance: d, docFreq: len(i.postings[t])})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if a.docFreq != b.docFreq {
+			return a.docFreq > b.docFreq
+		}
+		return a.term < b.term
+	})
+
+	var words []string
+	for _, c := range candidates {
+		if len(words) == n {
+			break
+		}
+		words = append(words, i.words[c.term])
+	}
+	return words
+}
+
+// editDistance returns the optimal string alignment distance between a and b (the number of
+// insertions, deletions, substitutions, and adjacent transpositions needed to turn a into b,
+// without editing any substring more than once). If the distance is greater than maxDistance, it
+// returns maxDistance+1.
+func editDistance(a, b string, maxDistance int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > maxDistance || -d > maxDistance {
+		return maxDistance + 1
+	}
+
+	// rows[k][j] is the distance between ra[:i-2+k] and rb[:j].
+	var rows [3][]int
+	for k := range rows {
+		rows[k] = make([]int, len(rb)+1)
+	}
+	for j := range rows[2] {
+		rows[2][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		rows[0], rows[1], rows[2] = rows[1], rows[2], rows[0]
+		cur, prev, prev2 := rows[2], rows[1], rows[0]
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > maxDistance {
+			return maxDistance + 1
+		}
+	}
+	return min(rows[2][len(rb)], maxDistance+1)
+}
+
+// HasTerm reports whether any document in the index contains the analyzed term.
+func (i *Index) HasTerm(term string) bool {
+	_, ok := i.postings[term]
+	return ok
+}
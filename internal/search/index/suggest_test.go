@@ -0,0 +1,69 @@
+package index
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"abc", "abd", 1},
+		{"abc", "ab", 1},
+		{"abc", "xabc", 1},
+		{"abc", "acb", 1},
+		{"ca", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"größe", "grösse", 2},
+	}
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b, 3); got != test.want {
+			t.Errorf("%q %q: got %d, want %d", test.a, test.b, got, test.want)
+		}
+		if test.want > 1 {
+			if got := editDistance(test.a, test.b, 1); got != 2 {
+				t.Errorf("%q %q with max distance 1: got %d, want 2", test.a, test.b, got)
+			}
+		}
+	}
+}
+
+func TestIndex_Suggest(t *testing.T) {
+	docs := []Document{
+		{ID: "a", Data: []byte("Authentication and authorization")},
+		{ID: "b", Data: []byte("authentication, authenticated")},
+		{ID: "c", Data: []byte("go code and the")},
+	}
+	idx, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range docs {
+		if err := idx.Add(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string][]string{
+		"authentc":  {"authenticated"}, // the stem of "authentcation" (the shortest word is shown)
+		"authoriz":  {"authorization"},
+		"cod":       {"code"},
+		"og":        nil, // too short
+		"e":         nil,
+		"thw":       nil, // stop words are not suggested
+		"xyz":       nil,
+		"zzzzzzzzz": nil,
+	}
+	for term, want := range tests {
+		t.Run(term, func(t *testing.T) {
+			if got := idx.Suggest(term, 3); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
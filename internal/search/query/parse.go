@@ -0,0 +1,294 @@
+package query
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/sourcegraph/docsite/internal/search/analysis"
+)
+
+// Node is a node in the syntax tree of a parsed query.
+type Node interface {
+	node()
+}
+
+// TermNode matches documents that contain a term, or a phrase of consecutive terms.
+type TermNode struct {
+	Text    string  // the term or phrase as written in the query (without quotes and field qualifier)
+	Quoted  bool    // whether the phrase was quoted
+	Terms   []Term  // the analyzed terms (more than 1 for a phrase)
+	Fields  []Field // the fields to match in (nil for all fields)
+	Synonym bool    // whether the term or phrase is a synonym of one in the query (see Synonyms)
+}
+
+// NotNode matches documents that do not match its operand.
+type NotNode struct {
+	Operand Node
+}
+
+// AndNode matches documents that match all of its operands.
+type AndNode struct {
+	Operands []Node
+}
+
+// OrNode matches documents that match any of its operands.
+type OrNode struct {
+	Operands []Node
+}
+
+func (TermNode) node() {}
+func (NotNode) node()  {}
+func (AndNode) node()  {}
+func (OrNode) node()   {}
+
+// Term is a single analyzed term in a query.
+type Term struct {
+	Text   string // the term text (see analysis.Analyze)
+	Prefix bool   // whether the term also matches all longer terms that begin with it
+}
+
+// qualifiers maps field qualifiers in queries (such as "title:") to the fields they match in.
+var qualifiers = map[string][]Field{
+	"title":    {FieldTitle},
+	"path":     {FieldPath},
+	"tag":      {FieldTags},
+	"category": {FieldCategory},
+	"code":     {FieldCode},
+}
+
+// parse parses a query string. The syntax is:
+//
+//	foo bar       documents containing both foo and bar
+//	"foo bar"     documents containing the exact phrase "foo bar"
+//	-foo          documents not containing foo
+//	foo OR bar    documents containing either foo or bar (OR binds tighter than the implicit AND)
+//	title:foo     documents containing foo in the title (also path:, tag:, category:, and code:)
+//	foo*          documents containing a word that begins with foo
+//
+// Words are analyzed in the same way as indexed text, so "configuring" also matches "configure"
+// and "Café" also matches "cafe" (see analysis.Analyze). An unquoted code identifier (such as
+// --max-workers or SRC_LOG_LEVEL) matches either the identifier in code or the phrase of its words
+// (see analysis.CodeTokens). Stop words (such as "the") are ignored,
+// unless they are quoted, qualified, or the query consists only of stop words.
+//
+// Words and phrases that have synonyms are expanded to also match their synonyms (see
+// Synonyms.expand).
+func parse(queryStr string, synonyms *Synonyms) Node {
+	p := parser{input: []rune(queryStr)}
+	var clauses []Node
+	for {
+		clause := p.parseClause()
+		if clause == nil {
+			break
+		}
+		clauses = append(clauses, clause)
+	}
+
+	var withoutStopWords AndNode
+	and := AndNode{Operands: synonyms.expand(clauses)}
+	for i, clause := range and.Operands {
+		and.Operands[i] = withCodeTerms(clause)
+	}
+	hasPositive := false
+	for _, clause := range and.Operands {
+		if node, ok := clause.(TermNode); ok && node.isStopWord() {
+			continue
+		}
+		withoutStopWords.Operands = append(withoutStopWords.Operands, clause)
+		if _, ok := clause.(NotNode); !ok {
+			hasPositive = true
+		}
+	}
+	if hasPositive {
+		return withoutStopWords
+	}
+	return and
+}
+
+type parser struct {
+	input    []rune
+	pos      int
+	excluded [][2]int // the byte ranges in the input of excluded operands
+}
+
+// excludedRanges returns the byte ranges in the query string of the operands that are excluded
+// (such as "-foo", `-"foo bar"`, and "-title:foo"), including their "-".
+func excludedRanges(queryStr string) [][2]int {
+	p := parser{input: []rune(queryStr)}
+	for p.parseClause() != nil {
+	}
+	return p.excluded
+}
+
+// parseClause parses a sequence of operands separated by OR. It returns nil at the end of the
+// input.
+func (p *parser) parseClause() Node {
+	var or OrNode
+	for {
+		operand := p.parseOperand()
+		if operand == nil {
+			break
+		}
+		or.Operands = append(or.Operands, operand)
+		if !p.consumeOr() {
+			break
+		}
+	}
+	switch len(or.Operands) {
+	case 0:
+		return nil
+	case 1:
+		return or.Operands[0]
+	}
+	return or
+}
+
+// parseOperand parses an optionally negated term or phrase. Operands without any terms (such as
+// punctuation) are skipped. It returns nil at the end of the input.
+func (p *parser) parseOperand() Node {
+	for {
+		p.skipSpace()
+		if p.pos == len(p.input) {
+			return nil
+		}
+
+		// A leading "--" (as in a command-line flag such as --max-workers) does not negate.
+		start := p.pos
+		negated := false
+		if p.input[p.pos] == '-' && p.pos+1 < len(p.input) && !unicode.IsSpace(p.input[p.pos+1]) && p.input[p.pos+1] != '-' {
+			negated = true
+			p.pos++
+		}
+
+		var fields []Field
+		if name, ok := p.peekQualifier(); ok {
+			fields = qualifiers[name]
+			p.pos += len([]rune(name)) + 1
+		}
+
+		var node TermNode
+		if p.pos < len(p.input) && p.input[p.pos] == '"' {
+			p.pos++
+			end := p.pos
+			for end < len(p.input) && p.input[end] != '"' {
+				end++
+			}
+			node = newTermNode(string(p.input[p.pos:end]), true, fields)
+			p.pos = end
+			if p.pos < len(p.input) {
+				p.pos++ // closing quote
+			}
+		} else {
+			node = newTermNode(p.readWord(), false, fields)
+		}
+		if len(node.Terms) == 0 {
+			continue
+		}
+		if negated {
+			p.excluded = append(p.excluded, [2]int{len(string(p.input[:start])), len(string(p.input[:p.pos]))})
+			return NotNode{Operand: node}
+		}
+		return node
+	}
+}
+
+func newTermNode(text string, quoted bool, fields []Field) TermNode {
+	tokens := analysis.Analyze(text)
+	terms := make([]Term, len(tokens))
+	for i, token := range tokens {
+		terms[i] = Term{Text: token.Term}
+	}
+	if n := len(tokens); n > 0 && !quoted && strings.HasSuffix(text, "*") {
+		// A prefix is not a word, so it is normalized but not stemmed.
+		last := tokens[n-1]
+		terms[n-1] = Term{Text: analysis.Normalize(text[last.Start:last.End]), Prefix: true}
+	}
+	return TermNode{Text: text, Quoted: quoted, Terms: terms, Fields: fields}
+}
+
+// withCodeTerms returns the node with each unquoted word that is a code identifier (see
+// analysis.CodeTokens) replaced by an OrNode that matches either the identifier in code or the
+// phrase of its words.
+func withCodeTerms(node Node) Node {
+	switch n := node.(type) {
+	case TermNode:
+		if n.Quoted || n.Synonym || n.Terms[len(n.Terms)-1].Prefix {
+			return n
+		}
+		if tokens := analysis.CodeTokens(n.Text); len(tokens) == 1 {
+			code := TermNode{Text: n.Text, Terms: []Term{{Text: tokens[0].Term}}, Fields: n.Fields}
+			return OrNode{Operands: []Node{code, n}}
+		}
+	case NotNode:
+		return NotNode{Operand: withCodeTerms(n.Operand)}
+	case AndNode:
+		operands := make([]Node, len(n.Operands))
+		for i, operand := range n.Operands {
+			operands[i] = withCodeTerms(operand)
+		}
+		return AndNode{Operands: operands}
+	case OrNode:
+		operands := make([]Node, len(n.Operands))
+		for i, operand := range n.Operands {
+			operands[i] = withCodeTerms(operand)
+		}
+		return OrNode{Operands: operands}
+	}
+	return node
+}
+
+// isStopWord reports whether the node is an unquoted and unqualified stop word.
+func (n TermNode) isStopWord() bool {
+	if n.Quoted || n.Fields != nil || n.Synonym {
+		return false
+	}
+	for _, term := range n.Terms {
+		if term.Prefix || !analysis.IsStopWord(term.Text) {
+			return false
+		}
+	}
+	return true
+}
+
+// peekQualifier reports whether the input at the current position begins with a known field
+// qualifier (such as "title:") that is followed by a term.
+func (p *parser) peekQualifier() (string, bool) {
+	end := p.pos
+	for end < len(p.input) && unicode.IsLetter(p.input[end]) {
+		end++
+	}
+	if end == len(p.input) || p.input[end] != ':' || end+1 == len(p.input) || unicode.IsSpace(p.input[end+1]) {
+		return "", false
+	}
+	name := strings.ToLower(string(p.input[p.pos:end]))
+	_, ok := qualifiers[name]
+	return name, ok
+}
+
+func (p *parser) readWord() string {
+	start := p.pos
+	for p.pos < len(p.input) && !unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+// consumeOr consumes the OR operator if it is next in the input (and is followed by an operand).
+func (p *parser) consumeOr() bool {
+	save := p.pos
+	p.skipSpace()
+	if p.readWord() == "OR" {
+		p.skipSpace()
+		if p.pos < len(p.input) {
+			return true
+		}
+	}
+	p.pos = save
+	return false
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
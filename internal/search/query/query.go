@@ -1,102 +1,283 @@
 package query
 
 import (
-	"math"
-	"path"
 	"sort"
 	"strings"
+
+	"github.com/sourcegraph/docsite/internal/search/analysis"
 )
 
 // Query is a search query.
 type Query struct {
-	input  string // the original query input string
-	tokens []token
+	input   string   // the original query input string
+	root    Node     // the parsed query
+	phrases []phrase // the terms and phrases that are not excluded (used to find matches in text)
+}
+
+// phrase is a term or phrase in a query, with the fields that it matches in.
+type phrase struct {
+	terms  []Term
+	fields []Field // nil for all fields
 }
 
-// Parse parses a search query string.
+// Parse parses a search query string. See parse for the syntax.
 func Parse(queryStr string) Query {
-	// Find unique token strings.
-	tokenStrs := strings.Fields(queryStr)
-	uniq := make(map[string]struct{}, len(tokenStrs))
-	for _, tokenStr := range tokenStrs {
-		uniq[strings.ToLower(tokenStr)] = struct{}{}
+	return ParseWithSynonyms(queryStr, nil)
+}
+
+// ParseWithSynonyms parses a search query string, expanding words and phrases that have synonyms
+// to also match their synonyms. Matches of synonyms are scored lower than matches of the words
+// and phrases in the query (see ScoreParams.SynonymBoost).
+func ParseWithSynonyms(queryStr string, synonyms *Synonyms) Query {
+	q := Query{
+		input: queryStr,
+		root:  parse(queryStr, synonyms),
 	}
 
-	tokens := make([]token, 0, len(uniq))
-	for tokenStr := range uniq {
-		tokens = append(tokens, newToken(tokenStr))
+	// Find unique positive terms and phrases.
+	uniq := map[string]struct{}{}
+	q.walkTerms(func(node TermNode, negated bool) {
+		if negated {
+			return
+		}
+		var key string
+		for _, term := range node.Terms {
+			key += term.Text
+			if term.Prefix {
+				key += "*"
+			}
+			key += " "
+		}
+		for _, field := range node.Fields {
+			key += ":" + field.String()
+		}
+		if _, seen := uniq[key]; !seen {
+			uniq[key] = struct{}{}
+			q.phrases = append(q.phrases, phrase{terms: node.Terms, fields: node.Fields})
+		}
+	})
+	return q
+}
+
+// String returns the original query string.
+func (q Query) String() string { return q.input }
+
+// Root returns the root node of the parsed query.
+func (q Query) Root() Node { return q.root }
+
+// walkTerms calls fn for each term node in the query, with whether the node is excluded (negated).
+func (q Query) walkTerms(fn func(node TermNode, negated bool)) {
+	var walk func(node Node, negated bool)
+	walk = func(node Node, negated bool) {
+		switch n := node.(type) {
+		case TermNode:
+			fn(n, negated)
+		case NotNode:
+			walk(n.Operand, !negated)
+		case AndNode:
+			for _, operand := range n.Operands {
+				walk(operand, negated)
+			}
+		case OrNode:
+			for _, operand := range n.Operands {
+				walk(operand, negated)
+			}
+		}
 	}
+	walk(q.root, false)
+}
+
+// Terms returns the distinct terms that are not excluded by the query. Every document that
+// matches the query contains at least one of these terms.
+func (q Query) Terms() []Term {
+	var terms []Term
+	seen := map[Term]struct{}{}
+	q.walkTerms(func(node TermNode, negated bool) {
+		if negated {
+			return
+		}
+		for _, term := range node.Terms {
+			if _, ok := seen[term]; !ok {
+				seen[term] = struct{}{}
+				terms = append(terms, term)
+			}
+		}
+	})
+	return terms
+}
+
+// Doc provides the positions of terms in a single document, for matching against a query.
+type Doc interface {
+	// Positions returns the sorted offsets of all occurrences in the field of the terms matched
+	// by term. The offsets of the heading, body, and code fields are offsets in the same text, so
+	// they are comparable.
+	Positions(field Field, term Term) []int
+}
+
+// textFields are the fields that are parts of the same text and share offsets.
+var textFields = []Field{FieldHeading, FieldBody, FieldCode}
 
-	return Query{
-		input:  queryStr,
-		tokens: tokens,
+// Match reports whether the document matches the query.
+func (q Query) Match(doc Doc) bool {
+	var match func(node Node) bool
+	match = func(node Node) bool {
+		switch n := node.(type) {
+		case TermNode:
+			if n.Fields != nil {
+				return matchPhrase(doc, n.Fields, n.Terms)
+			}
+			for f := Field(0); int(f) < NumFields; f++ {
+				if !isTextField(f) && matchPhrase(doc, []Field{f}, n.Terms) {
+					return true
+				}
+			}
+			return matchPhrase(doc, textFields, n.Terms)
+		case NotNode:
+			return !match(n.Operand)
+		case AndNode:
+			for _, operand := range n.Operands {
+				if !match(operand) {
+					return false
+				}
+			}
+			return len(n.Operands) > 0
+		case OrNode:
+			for _, operand := range n.Operands {
+				if match(operand) {
+					return true
+				}
+			}
+			return false
+		}
+		return false
 	}
+	return match(q.root)
 }
 
-// Match reports whether the path or text contains at least 1 match of the query.
-func (q Query) Match(pathStr string, text []byte) bool {
-	name := path.Base(pathStr)
+func isTextField(field Field) bool {
+	return containsField(textFields, field)
+}
 
-	for _, token := range q.tokens {
-		if token.pattern.MatchString(name) {
-			return true
+// matchPhrase reports whether the terms occur consecutively (in order) in the fields.
+func matchPhrase(doc Doc, fields []Field, terms []Term) bool {
+	positions := make([]map[int]struct{}, len(terms))
+	for i, term := range terms {
+		positions[i] = map[int]struct{}{}
+		for _, field := range fields {
+			for _, pos := range doc.Positions(field, term) {
+				positions[i][pos] = struct{}{}
+			}
 		}
-		if token.pattern.Match(text) {
+		if len(positions[i]) == 0 {
+			return false
+		}
+	}
+	for start := range positions[0] {
+		found := true
+		for i := 1; i < len(terms) && found; i++ {
+			_, found = positions[i][start+i]
+		}
+		if found {
 			return true
 		}
 	}
 	return false
 }
 
-const maxMatchesPerDoc = 50
-
-// Score scores the query match against the path and text.
-func (q Query) Score(pathStr string, text []byte) float64 {
-	name := path.Base(pathStr)
+// Match is an array of [start, end] byte indexes for a match.
+type Match [2]int
 
-	tokensInName := 0
-	tokensMatching := 0
-	totalMatches := 0
-	for _, token := range q.tokens {
-		if token.pattern.MatchString(name) {
-			tokensInName++
+// FindAllIndex returns a slice of all indexes in the text (which is in the field) of matches of the
+// query's terms and phrases that are not excluded. Terms and phrases with a field qualifier (such as
+// "title:foo") only match in text of their fields. The text is analyzed in the same way as indexed
+// text, so only whole words match.
+func (q Query) FindAllIndex(text string, field Field) []Match {
+	tokens := analysis.Analyze(text)
+	var (
+		matches    []Match
+		codeTokens []analysis.Token // only computed if needed
+	)
+	for _, p := range q.phrases {
+		if p.fields != nil && !containsField(p.fields, field) {
+			continue
+		}
+		phrase := p.terms
+		if len(phrase) == 1 && analysis.IsCodeTerm(phrase[0].Text) {
+			if codeTokens == nil {
+				codeTokens = analysis.CodeTokens(text)
+			}
+			for _, token := range codeTokens {
+				if token.Term == phrase[0].Text {
+					matches = append(matches, Match{token.Start, token.End})
+				}
+			}
+			continue
 		}
-		count := len(token.pattern.FindAllIndex(text, maxMatchesPerDoc))
-		if count > 0 {
-			tokensMatching++
+		for i := 0; i+len(phrase) <= len(tokens); i++ {
+			if matchTokens(text, tokens[i:i+len(phrase)], phrase) {
+				matches = append(matches, Match{tokens[i].Start, tokens[i+len(phrase)-1].End})
+			}
 		}
-		totalMatches += count
 	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i][0] < matches[j][0] || (matches[i][0] == matches[j][0] && matches[i][1] < matches[j][1])
+	})
 
-	return float64(tokensInName*500) + float64(float64(tokensMatching)*50*math.Pow(4, float64(tokensMatching))) + float64(totalMatches)/float64(len(text)+1)
+	// Merge overlapping matches (such as a code identifier and the phrase of its words).
+	merged := matches[:0]
+	for _, m := range matches {
+		if n := len(merged); n > 0 && m[0] < merged[n-1][1] {
+			merged[n-1][1] = max(merged[n-1][1], m[1])
+			continue
+		}
+		merged = append(merged, m)
+	}
+	return merged
 }
 
-// Match is an array of [start, end] byte indexes for a match.
-type Match [2]int
+// matchTokens reports whether each token in text matches the corresponding term.
+func matchTokens(text string, tokens []analysis.Token, terms []Term) bool {
+	for i, term := range terms {
+		if term.Prefix {
+			if !strings.HasPrefix(analysis.Normalize(text[tokens[i].Start:tokens[i].End]), term.Text) {
+				return false
+			}
+		} else if tokens[i].Term != term.Text {
+			return false
+		}
+	}
+	return true
+}
 
-// FindAllIndex returns a slice of all query match indexes in the text.
-func (q Query) FindAllIndex(text string) []Match {
-	findTokenAllIndex := func(token token) []Match {
-		var matches []Match
-		c := 0
-		for c < len(text) {
-			m := token.pattern.FindStringIndex(text[c:])
-			if m == nil {
-				break
+// Respell returns the query string with words replaced by other words. It calls respell with the
+// analyzed term of each word that is not a stop word, field qualifier, prefix, or excluded, and
+// replaces the word with the returned word (unless it is empty).
+func (q Query) Respell(respell func(term string) string) string {
+	excluded := excludedRanges(q.input)
+	isExcluded := func(token analysis.Token) bool {
+		for _, r := range excluded {
+			if token.Start >= r[0] && token.End <= r[1] {
+				return true
 			}
-			start, end := c+m[0], c+m[1]
-			matches = append(matches, Match{start, end})
-			c = end
 		}
-		return matches
+		return false
 	}
 
-	var matches []Match
-	for _, token := range q.tokens {
-		matches = append(matches, findTokenAllIndex(token)...)
+	var b strings.Builder
+	var last int
+	for _, token := range analysis.Analyze(q.input) {
+		if analysis.IsStopWord(token.Term) || isExcluded(token) {
+			continue
+		}
+		if rest := q.input[token.End:]; strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, "*") {
+			continue
+		}
+		if word := respell(token.Term); word != "" {
+			b.WriteString(q.input[last:token.Start])
+			b.WriteString(word)
+			last = token.End
+		}
 	}
-	sort.Slice(matches, func(i, j int) bool {
-		return matches[i][0] < matches[j][0] || (matches[i][0] == matches[j][0] && matches[i][1] < matches[j][1])
-	})
-	return matches
+	b.WriteString(q.input[last:])
+	return b.String()
 }
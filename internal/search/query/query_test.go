@@ -3,12 +3,15 @@ package query
 import (
 	"reflect"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestQuery_FindAllIndex(t *testing.T) {
 	tests := map[string]struct {
 		text  string
 		query string
+		field string // the name of the text's field (body if empty)
 		want  []Match
 	}{
 		"simple": {
@@ -16,10 +19,10 @@ func TestQuery_FindAllIndex(t *testing.T) {
 			query: "aa",
 			want:  []Match{{0, 2}, {6, 8}},
 		},
-		"token substring of another token": {
-			text:  "aa",
-			query: "a aa",
-			want:  []Match{{0, 1}, {0, 2}, {1, 2}},
+		"token substring of another word": {
+			text:  "xx xxx",
+			query: "xx x",
+			want:  []Match{{0, 2}},
 		},
 		"token substring of another token with only shorter match": {
 			text:  "ab",
@@ -37,8 +40,8 @@ func TestQuery_FindAllIndex(t *testing.T) {
 			want:  []Match{{3, 5}, {6, 8}},
 		},
 		"case-insensitive": {
-			text:  "a A b B",
-			query: "a B",
+			text:  "x X b B",
+			query: "x B",
 			want:  []Match{{0, 1}, {2, 3}, {4, 5}, {6, 7}},
 		},
 		"wide chars": {
@@ -46,14 +49,181 @@ func TestQuery_FindAllIndex(t *testing.T) {
 			query: "a",
 			want:  []Match{{0, 1}, {6, 7}},
 		},
+		"excluded token": {
+			text:  "aa bb",
+			query: "aa -bb",
+			want:  []Match{{0, 2}},
+		},
+		"phrase": {
+			text:  "aa  bb aa cc",
+			query: `"aa bb"`,
+			want:  []Match{{0, 6}},
+		},
+		"field qualifier": {
+			text:  "aa bb",
+			query: "title:bb",
+			field: "title",
+			want:  []Match{{3, 5}},
+		},
+		"field qualifier in other field": {
+			text:  "aa bb",
+			query: "aa title:bb",
+			want:  []Match{{0, 2}},
+		},
+		"code qualifier": {
+			text:  "aa bb",
+			query: "code:aa",
+			field: "code",
+			want:  []Match{{0, 2}},
+		},
+		"or": {
+			text:  "aa bb cc",
+			query: "aa OR cc",
+			want:  []Match{{0, 2}, {6, 8}},
+		},
+		"stemming": {
+			text:  "Configure configuring configured",
+			query: "configuration",
+			want:  []Match{{0, 9}, {10, 21}, {22, 32}},
+		},
+		"accents": {
+			text:  "café Cafe",
+			query: "CAFÉ",
+			want:  []Match{{0, 5}, {6, 10}},
+		},
+		"whole words": {
+			text:  "go google algorithm",
+			query: "go",
+			want:  []Match{{0, 2}},
+		},
+		"prefix": {
+			text:  "go google algorithm",
+			query: "goo*",
+			want:  []Match{{3, 9}},
+		},
+		"stop words": {
+			text:  "the site",
+			query: "the site",
+			want:  []Match{{4, 8}},
+		},
+		"only stop words": {
+			text:  "the site",
+			query: "the",
+			want:  []Match{{0, 3}},
+		},
+		"phrase with stop word": {
+			text:  "run the site",
+			query: `"running the sites"`,
+			want:  []Match{{0, 12}},
+		},
+		"code identifier": {
+			text:  "Set --max-workers or max workers",
+			query: "--max-workers",
+			want:  []Match{{4, 17}, {21, 32}},
+		},
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
+			field := FieldBody
+			if test.field != "" {
+				field, _ = FieldByName(test.field)
+			}
 			q := Parse(test.query)
-			matches := q.FindAllIndex(test.text)
+			matches := q.FindAllIndex(test.text, field)
 			if !reflect.DeepEqual(matches, test.want) {
 				t.Errorf("got matches %v, want %v", matches, test.want)
 			}
 		})
 	}
 }
+
+func TestParse(t *testing.T) {
+	term := func(text string, fields ...Field) TermNode {
+		return newTermNode(text, false, fields)
+	}
+	phrase := func(text string, fields ...Field) TermNode {
+		return newTermNode(text, true, fields)
+	}
+	and := func(operands ...Node) Node { return AndNode{Operands: operands} }
+	code := func(text, term string, fields ...Field) Node {
+		return OrNode{Operands: []Node{TermNode{Text: text, Terms: []Term{{Text: term}}, Fields: fields}, newTermNode(text, false, fields)}}
+	}
+
+	tests := map[string]Node{
+		"":                 AndNode{},
+		"a":                and(term("a")),
+		"x  B":             and(term("x"), term("B")),
+		`"a b"`:            and(phrase("a b")),
+		`"a b`:             and(phrase("a b")),
+		`x "a b" y`:        and(term("x"), phrase("a b"), term("y")),
+		"-a b":             and(NotNode{Operand: term("a")}, term("b")),
+		`-"a b"`:           and(NotNode{Operand: phrase("a b")}),
+		"x - b":            and(term("x"), term("b")),
+		"a OR b":           and(OrNode{Operands: []Node{term("a"), term("b")}}),
+		"a OR b c":         and(OrNode{Operands: []Node{term("a"), term("b")}}, term("c")),
+		"a OR -b OR c":     and(OrNode{Operands: []Node{term("a"), NotNode{Operand: term("b")}, term("c")}}),
+		"x or b":           and(term("x"), term("b")),
+		"a OR":             and(term("a"), term("OR")),
+		"title:a":          and(term("a", FieldTitle)),
+		`Title:"a b"`:      and(phrase("a b", FieldTitle)),
+		"-path:a":          and(NotNode{Operand: term("a", FieldPath)}),
+		"tag:a category:b": and(term("a", FieldTags), term("b", FieldCategory)),
+		"title: x":         and(term("title:"), term("x")),
+		"foo:a":            and(term("foo:a")),
+		"a.b":              and(code("a.b", "a.b")),
+		"--max-workers":    and(code("--max-workers", "--max-workers")),
+		"SRC_LOG_LEVEL":    and(code("SRC_LOG_LEVEL", "src_log_level")),
+		"code:foo_bar":     and(code("foo_bar", "foo_bar", FieldCode)),
+		"code:foo":         and(term("foo", FieldCode)),
+		`"a.b"`:            and(phrase("a.b")),
+		"a.b*":             and(TermNode{Text: "a.b*", Terms: []Term{{Text: "a"}, {Text: "b", Prefix: true}}}),
+		"-- . a":           and(term("a")),
+		"the x":            and(term("x")),
+		"the":              and(term("the")),
+		"the -x":           and(term("the"), NotNode{Operand: term("x")}),
+		`the "the x"`:      and(phrase("the x")),
+		"title:the x":      and(term("the", FieldTitle), term("x")),
+		"x*":               and(TermNode{Text: "x*", Terms: []Term{{Text: "x", Prefix: true}}}),
+		"Configur*":        and(TermNode{Text: "Configur*", Terms: []Term{{Text: "configur", Prefix: true}}}),
+	}
+	for queryStr, want := range tests {
+		t.Run(queryStr, func(t *testing.T) {
+			got := Parse(queryStr).Root()
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestQuery_Terms(t *testing.T) {
+	got := Parse(`x "b c" -d x e* OR -f title:x the`).Terms()
+	want := []Term{{Text: "x"}, {Text: "b"}, {Text: "c"}, {Text: "e", Prefix: true}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestQuery_Respell(t *testing.T) {
+	respellings := map[string]string{"foo": "food", "bar": "baz"}
+	tests := map[string]string{
+		"":                  "",
+		"foo":               "food",
+		"Foo bar qux":       "food baz qux",
+		`"foo bar" -bar`:    `"food baz" -bar`,
+		"foo OR foo:bar":    "food OR foo:baz",
+		"foo* title:bar":    "foo* title:baz",
+		"the foo-bar, baz!": "the food-baz, baz!",
+		`foo -"foo bar"`:    `food -"foo bar"`,
+		`-"bar foo`:         `-"bar foo`,
+		"-title:bar foo":    "-title:bar food",
+	}
+	for queryStr, want := range tests {
+		t.Run(queryStr, func(t *testing.T) {
+			got := Parse(queryStr).Respell(func(term string) string { return respellings[term] })
+			if got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
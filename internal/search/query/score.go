@@ -0,0 +1,185 @@
+package query
+
+import (
+	"fmt"
+	"math"
+)
+
+// Field is a part of a document whose terms are counted (and boosted) separately when scoring.
+type Field int
+
+const (
+	FieldTitle    Field = iota // the document title
+	FieldHeading               // section headings
+	FieldPath                  // the URL path
+	FieldBody                  // body text
+	FieldCode                  // code blocks and code spans
+	FieldTags                  // tags in the document metadata
+	FieldCategory              // category in the document metadata
+	FieldKeywords              // search keywords in the document metadata
+
+	// NumFields is the number of fields.
+	NumFields = int(FieldKeywords) + 1
+)
+
+var fieldNames = [NumFields]string{
+	FieldTitle:    "title",
+	FieldHeading:  "headings",
+	FieldPath:     "path",
+	FieldBody:     "body",
+	FieldCode:     "code",
+	FieldTags:     "tags",
+	FieldCategory: "category",
+	FieldKeywords: "keywords",
+}
+
+func (f Field) String() string {
+	if f < 0 || int(f) >= NumFields {
+		return fmt.Sprintf("Field(%d)", int(f))
+	}
+	return fieldNames[f]
+}
+
+// FieldByName returns the field with the given name (such as "title" or "code").
+func FieldByName(name string) (Field, bool) {
+	for f, fieldName := range fieldNames {
+		if fieldName == name {
+			return Field(f), true
+		}
+	}
+	return 0, false
+}
+
+// Stats provides the term statistics of a single document, and of the corpus that it belongs to,
+// that are needed to score it.
+type Stats interface {
+	// NumDocs returns the number of documents in the corpus.
+	NumDocs() int
+
+	// DocFreq returns the number of documents in the corpus that contain the term in any field.
+	DocFreq(term Term) int
+
+	// TermFreq returns the number of occurrences of the term in the document's field.
+	TermFreq(field Field, term Term) int
+
+	// FieldLen returns the number of terms in the document's field.
+	FieldLen(field Field) int
+
+	// AvgFieldLen returns the average number of terms in the field over all documents in the
+	// corpus.
+	AvgFieldLen(field Field) float64
+}
+
+// ScoreParams are the parameters for scoring documents with BM25F, a variant of the BM25 ranking
+// function that weights fields differently.
+type ScoreParams struct {
+	K1     float64            // term frequency saturation
+	B      float64            // field length normalization (0 to 1)
+	Boosts [NumFields]float64 // weight of each field (0 to ignore the field)
+
+	SynonymBoost float64 // weight of terms that are synonyms of words in the query (see Synonyms)
+}
+
+// DefaultScoreParams returns the default parameters for scoring.
+func DefaultScoreParams() ScoreParams {
+	return ScoreParams{
+		K1: 1.2,
+		B:  0.75,
+		Boosts: [NumFields]float64{
+			FieldTitle:    5,
+			FieldHeading:  3,
+			FieldPath:     4,
+			FieldBody:     1,
+			FieldCode:     0.5,
+			FieldTags:     2,
+			FieldCategory: 1,
+			FieldKeywords: 5,
+		},
+		SynonymBoost: 0.5,
+	}
+}
+
+// Score scores the document described by stats against the query. Only terms that are not
+// excluded by the query contribute to the score. Rare terms score higher than common terms, and
+// the term frequencies in each field are weighted by the field's boost and normalized by the
+// field's length. Terms with a field qualifier are only counted in that field, and synonyms are
+// weighted by the synonym boost.
+func (q Query) Score(stats Stats, params ScoreParams) float64 {
+	numDocs := float64(stats.NumDocs())
+
+	type scoredTerm struct {
+		term   Term
+		fields string
+	}
+	var (
+		keys    []scoredTerm // in order of first occurrence, for a deterministic sum
+		weights = map[scoredTerm]float64{}
+		fields  = map[scoredTerm][]Field{}
+	)
+	q.walkTerms(func(node TermNode, negated bool) {
+		if negated {
+			return
+		}
+		weight := 1.0
+		if node.Synonym {
+			// Average the terms of a synonym phrase, so that a long synonym (such as "single
+			// sign-on" for "SSO") doesn't outscore the word in the query.
+			weight = params.SynonymBoost / float64(len(node.Terms))
+		}
+		for _, term := range node.Terms {
+			key := scoredTerm{term: term, fields: fmt.Sprint(node.Fields)}
+			if w, ok := weights[key]; !ok {
+				keys = append(keys, key)
+				weights[key] = weight
+				fields[key] = node.Fields
+			} else if weight > w {
+				// A term that is both in the query and a synonym is scored as in the query.
+				weights[key] = weight
+			}
+		}
+	})
+
+	var score float64
+	for _, key := range keys {
+		score += weights[key] * scoreTerm(stats, params, numDocs, key.term, fields[key])
+	}
+	return score
+}
+
+func scoreTerm(stats Stats, params ScoreParams, numDocs float64, term Term, fields []Field) float64 {
+	df := float64(stats.DocFreq(term))
+	if df == 0 {
+		return 0
+	}
+	idf := math.Log(1 + (numDocs-df+0.5)/(df+0.5))
+
+	var tf float64
+	for f := Field(0); int(f) < NumFields; f++ {
+		if fields != nil && !containsField(fields, f) {
+			continue
+		}
+		boost := params.Boosts[f]
+		if boost == 0 {
+			continue
+		}
+		freq := stats.TermFreq(f, term)
+		if freq == 0 {
+			continue
+		}
+		norm := 1 - params.B
+		if avg := stats.AvgFieldLen(f); avg > 0 {
+			norm += params.B * float64(stats.FieldLen(f)) / avg
+		}
+		tf += boost * float64(freq) / norm
+	}
+	return idf * tf * (params.K1 + 1) / (params.K1 + tf)
+}
+
+func containsField(fields []Field, field Field) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package query
+
+import "testing"
+
+// fakeStats implements Stats for a single document.
+type fakeStats struct {
+	numDocs     int
+	docFreqs    map[string]int
+	termFreqs   map[Field]map[string]int
+	fieldLens   map[Field]int
+	avgFieldLen float64
+}
+
+func (s fakeStats) NumDocs() int                        { return s.numDocs }
+func (s fakeStats) DocFreq(term Term) int               { return s.docFreqs[term.Text] }
+func (s fakeStats) TermFreq(field Field, term Term) int { return s.termFreqs[field][term.Text] }
+func (s fakeStats) FieldLen(field Field) int            { return s.fieldLens[field] }
+func (s fakeStats) AvgFieldLen(Field) float64           { return s.avgFieldLen }
+
+func TestQuery_Score(t *testing.T) {
+	params := DefaultScoreParams()
+	score := func(queryStr string, stats fakeStats) float64 {
+		return Parse(queryStr).Score(stats, params)
+	}
+	base := fakeStats{
+		numDocs:     10,
+		docFreqs:    map[string]int{"x": 1, "b": 9},
+		termFreqs:   map[Field]map[string]int{FieldBody: {"x": 1, "b": 1}},
+		fieldLens:   map[Field]int{FieldBody: 10},
+		avgFieldLen: 10,
+	}
+
+	if got := score("c", base); got != 0 {
+		t.Errorf("got score %v for non-matching query, want 0", got)
+	}
+	if a, b := score("x", base), score("b", base); a <= b {
+		t.Errorf("got score %v for rare term <= score %v for common term", a, b)
+	}
+	if a, ab := score("x", base), score("x b", base); ab <= a {
+		t.Errorf("got score %v for 2 matching terms <= score %v for 1 matching term", ab, a)
+	}
+
+	inTitle := base
+	inTitle.termFreqs = map[Field]map[string]int{FieldTitle: {"x": 1}}
+	inTitle.fieldLens = map[Field]int{FieldTitle: 10}
+	if body, title := score("x", base), score("x", inTitle); title <= body {
+		t.Errorf("got score %v for title match <= score %v for body match", title, body)
+	}
+
+	long := base
+	long.fieldLens = map[Field]int{FieldBody: 100}
+	if short, long := score("x", base), score("x", long); long >= short {
+		t.Errorf("got score %v for long field >= score %v for short field", long, short)
+	}
+
+	ignored := params
+	ignored.Boosts[FieldBody] = 0
+	if got := Parse("x").Score(base, ignored); got != 0 {
+		t.Errorf("got score %v for match in field with zero boost, want 0", got)
+	}
+}
+
+func TestFieldByName(t *testing.T) {
+	for f := Field(0); int(f) < NumFields; f++ {
+		if got, ok := FieldByName(f.String()); !ok || got != f {
+			t.Errorf("%s: got %v (ok=%v), want %v", f, got, ok, f)
+		}
+	}
+	if _, ok := FieldByName("x"); ok {
+		t.Error("got ok for unknown field name")
+	}
+}
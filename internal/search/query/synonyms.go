@@ -0,0 +1,187 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/docsite/internal/search/analysis"
+)
+
+// Synonyms are groups of equivalent words and phrases (such as "SSO" and "single sign-on"). Words
+// and phrases in queries are expanded to also match their synonyms (see ParseWithSynonyms).
+type Synonyms struct {
+	synonyms map[string][]synonym // the synonyms of each word or phrase (keyed by phraseKey)
+	maxTerms int                  // the maximum number of terms in a word or phrase with synonyms
+}
+
+// synonym is a word or phrase that is equivalent to another.
+type synonym struct {
+	text  string // the word or phrase as written in the synonyms file
+	terms []Term // the analyzed terms
+}
+
+// ParseSynonyms parses a list of synonyms. Each line is a comma-separated group of equivalent
+// words or phrases, such as:
+//
+//	sso, single sign-on, saml, auth provider
+//
+// Empty lines and lines beginning with "#" are ignored. A word or phrase may be in multiple
+// groups, in which case it is equivalent to the words and phrases of all of them.
+func ParseSynonyms(data []byte) (*Synonyms, error) {
+	s := &Synonyms{synonyms: map[string][]synonym{}}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var group []synonym
+		for _, entry := range strings.Split(text, ",") {
+			entry = strings.TrimSpace(entry)
+			terms := analysis.Tokenize(entry)
+			if len(terms) == 0 {
+				return nil, errors.Errorf("synonyms line %d: empty word or phrase", line)
+			}
+			syn := synonym{text: entry, terms: make([]Term, len(terms))}
+			for i, term := range terms {
+				syn.terms[i] = Term{Text: term}
+			}
+			group = append(group, syn)
+		}
+		if len(group) < 2 {
+			return nil, errors.Errorf("synonyms line %d: want at least 2 comma-separated words or phrases", line)
+		}
+
+		for _, a := range group {
+			key := phraseKey(a.terms)
+			for _, b := range group {
+				if bKey := phraseKey(b.terms); bKey != key && !s.has(key, bKey) {
+					s.synonyms[key] = append(s.synonyms[key], b)
+				}
+			}
+			s.maxTerms = max(s.maxTerms, len(a.terms))
+		}
+	}
+	return s, scanner.Err()
+}
+
+// MergeSynonyms returns the synonyms of all of the lists (such as those of multiple content
+// versions), which may be nil. A word or phrase is equivalent to its synonyms in any of them.
+func MergeSynonyms(lists ...*Synonyms) *Synonyms {
+	s := &Synonyms{synonyms: map[string][]synonym{}}
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+		for key, syns := range list.synonyms {
+			for _, syn := range syns {
+				if !s.has(key, phraseKey(syn.terms)) {
+					s.synonyms[key] = append(s.synonyms[key], syn)
+				}
+			}
+		}
+		s.maxTerms = max(s.maxTerms, list.maxTerms)
+	}
+	return s
+}
+
+// has reports whether the word or phrase with key already has the synonym with synonymKey.
+func (s *Synonyms) has(key, synonymKey string) bool {
+	for _, syn := range s.synonyms[key] {
+		if phraseKey(syn.terms) == synonymKey {
+			return true
+		}
+	}
+	return false
+}
+
+func phraseKey(terms []Term) string {
+	texts := make([]string, len(terms))
+	for i, term := range terms {
+		texts[i] = term.Text
+	}
+	return strings.Join(texts, " ")
+}
+
+// expand returns the operands with each word or phrase that has synonyms replaced by an OrNode
+// that matches either it or any of its synonyms. A phrase may consist of multiple consecutive
+// operands (as in the unquoted query `single sign-on`). Only unquoted and unqualified words that
+// are not prefixes are expanded, and excluded words are not expanded.
+func (s *Synonyms) expand(operands []Node) []Node {
+	if s == nil || len(s.synonyms) == 0 {
+		return operands
+	}
+	var expanded []Node
+	for i := 0; i < len(operands); {
+		if node, n := s.expandRun(operands[i:]); n > 0 {
+			expanded = append(expanded, node)
+			i += n
+			continue
+		}
+		if or, ok := operands[i].(OrNode); ok {
+			operands := make([]Node, len(or.Operands))
+			for j, operand := range or.Operands {
+				operands[j] = operand
+				if node, n := s.expandRun(or.Operands[j : j+1]); n > 0 {
+					operands[j] = node
+				}
+			}
+			expanded = append(expanded, OrNode{Operands: operands})
+		} else {
+			expanded = append(expanded, operands[i])
+		}
+		i++
+	}
+	return expanded
+}
+
+// expandRun expands the longest run of operands at the start of operands whose terms are a word or
+// phrase with synonyms. It returns the expanded node and the number of operands in the run (0 if
+// no run has synonyms).
+func (s *Synonyms) expandRun(operands []Node) (Node, int) {
+	var (
+		terms []Term
+		ends  []int // the number of terms after each operand in the run
+	)
+	for _, operand := range operands {
+		node, ok := operand.(TermNode)
+		if !ok || !node.expandable() || len(terms)+len(node.Terms) > s.maxTerms {
+			break
+		}
+		terms = append(terms, node.Terms...)
+		ends = append(ends, len(terms))
+	}
+	for n := len(ends); n > 0; n-- {
+		synonyms, ok := s.synonyms[phraseKey(terms[:ends[n-1]])]
+		if !ok {
+			continue
+		}
+		var literal Node = operands[0]
+		if n > 1 {
+			literal = AndNode{Operands: operands[:n:n]}
+		}
+		or := OrNode{Operands: []Node{literal}}
+		for _, syn := range synonyms {
+			or.Operands = append(or.Operands, TermNode{Text: syn.text, Terms: syn.terms, Synonym: true})
+		}
+		return or, n
+	}
+	return nil, 0
+}
+
+// expandable reports whether the node's terms may be expanded to their synonyms.
+func (n TermNode) expandable() bool {
+	if n.Quoted || n.Fields != nil || n.Synonym {
+		return false
+	}
+	for _, term := range n.Terms {
+		if term.Prefix {
+			return false
+		}
+	}
+	return true
+}
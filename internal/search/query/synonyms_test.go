@@ -0,0 +1,102 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSynonyms(t *testing.T) {
+	tests := map[string]bool{ // synonyms file -> whether it is valid
+		"":                      true,
+		"# comment\n\na, b\n":   true,
+		"sso, single sign-on\n": true,
+		"a":                     false,
+		"a, , b":                false,
+		"a, ...":                false,
+	}
+	for data, wantOK := range tests {
+		t.Run(data, func(t *testing.T) {
+			_, err := ParseSynonyms([]byte(data))
+			if ok := err == nil; ok != wantOK {
+				t.Errorf("got error %v, want ok=%v", err, wantOK)
+			}
+		})
+	}
+}
+
+func TestMergeSynonyms(t *testing.T) {
+	parse := func(data string) *Synonyms {
+		t.Helper()
+		synonyms, err := ParseSynonyms([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return synonyms
+	}
+	want := parse("sso, single sign-on\nsso, saml\nx, y")
+	got := MergeSynonyms(parse("sso, single sign-on\nx, y"), nil, parse("sso, saml\nx, y"))
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(Synonyms{}, synonym{})); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseWithSynonyms(t *testing.T) {
+	synonyms, err := ParseSynonyms([]byte("sso, single sign-on, saml\nsaml, security assertion markup language\nx, y"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	term := func(text string) TermNode { return newTermNode(text, false, nil) }
+	synonym := func(text string) TermNode {
+		n := newTermNode(text, false, nil)
+		n.Synonym = true
+		return n
+	}
+	or := func(operands ...Node) Node { return OrNode{Operands: operands} }
+	and := func(operands ...Node) Node { return AndNode{Operands: operands} }
+
+	tests := map[string]Node{
+		"SSO setup":           and(or(term("SSO"), synonym("single sign-on"), synonym("saml")), term("setup")),
+		"single sign-on":      and(or(and(term("single"), or(TermNode{Text: "sign-on", Terms: []Term{{Text: "sign-on"}}}, term("sign-on"))), synonym("sso"), synonym("saml"))),
+		"saml":                and(or(term("saml"), synonym("sso"), synonym("single sign-on"), synonym("security assertion markup language"))),
+		"single":              and(term("single")),
+		"x OR z":              and(or(or(term("x"), synonym("y")), term("z"))),
+		`"sso" -x title:y z*`: and(newTermNode("sso", true, nil), NotNode{Operand: term("x")}, newTermNode("y", false, []Field{FieldTitle}), TermNode{Text: "z*", Terms: []Term{{Text: "z", Prefix: true}}}),
+	}
+	for queryStr, want := range tests {
+		t.Run(queryStr, func(t *testing.T) {
+			got := ParseWithSynonyms(queryStr, synonyms).Root()
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("highlight", func(t *testing.T) {
+		got := ParseWithSynonyms("sso", synonyms).FindAllIndex("Configure single sign-on.", FieldBody)
+		if want := []Match{{10, 24}}; !cmp.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
+
+func TestQuery_Score_synonyms(t *testing.T) {
+	synonyms, err := ParseSynonyms([]byte("x, y"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := func(term string) fakeStats {
+		return fakeStats{
+			numDocs:     10,
+			docFreqs:    map[string]int{"x": 1, "y": 1},
+			termFreqs:   map[Field]map[string]int{FieldBody: {term: 1}},
+			fieldLens:   map[Field]int{FieldBody: 10},
+			avgFieldLen: 10,
+		}
+	}
+	q := ParseWithSynonyms("x", synonyms)
+	literal, synonym := q.Score(stats("x"), DefaultScoreParams()), q.Score(stats("y"), DefaultScoreParams())
+	if synonym <= 0 || synonym >= literal {
+		t.Errorf("got score %v for synonym match, want less than %v for literal match (and > 0)", synonym, literal)
+	}
+}
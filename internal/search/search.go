@@ -1,42 +1,182 @@
 package search
 
 import (
+	"sort"
+
 	"github.com/pkg/errors"
 
 	"github.com/sourcegraph/docsite/internal/search/index"
 	"github.com/sourcegraph/docsite/internal/search/query"
 )
 
+// Options configures a search.
+type Options struct {
+	Offset int // the number of (highest-ranked) document results to skip
+	Limit  int // the maximum number of document results to return (0 for no limit)
+
+	Filters Filters // the selected facet values
+
+	// Pinned are the IDs of documents to rank first (in order), whether or not they match the query.
+	// IDs of documents that are not in the index are ignored.
+	Pinned []index.DocID
+}
+
 // Result is the result of a search.
 type Result struct {
-	DocumentResults []DocumentResult // document results
+	Query           query.Query      // the parsed query (with synonyms expanded), for highlighting matches
+	DocumentResults []DocumentResult // document results (only those in the range given by Offset and Limit)
 	Total           int              // total number of document results
+	Offset          int              // the number of document results that were skipped
+	Limit           int              // the maximum number of document results (0 for no limit)
+	HasMore         bool             // whether there are more document results after DocumentResults
+	Facets          Facets           // the facets of the document results
+	Filters         Filters          // the selected facet values
+	Suggestions     []string         // similarly spelled queries that have results (if there are no results)
 }
 
 // DocumentResult is the result of a search for a single document
 type DocumentResult struct {
 	index.DocumentResult
+	Versions       []string // the content versions with matches in the document (only for SearchVersions)
+	Pinned         bool     // whether the document is ranked first because it is pinned (see Options.Pinned)
 	SectionResults []SectionResult
 }
 
-func Search(query query.Query, index *index.Index) (*Result, error) {
-	result0, err := index.Search(query)
+// Search searches the index for the query. Documents are ranked using the score parameters, and
+// section results are only computed for the documents in the range given by the options.
+func Search(query query.Query, idx *index.Index, params query.ScoreParams, opt Options) (*Result, error) {
+	result0, err := idx.Search(query, params)
 	if err != nil {
 		return nil, err
 	}
+	drs := make([]DocumentResult, len(result0.DocumentResults))
+	for i, dr := range result0.DocumentResults {
+		drs[i] = DocumentResult{DocumentResult: dr}
+	}
+	drs = pin(drs, opt.Pinned, func(id index.DocID) (DocumentResult, bool) {
+		doc, ok := idx.Document(id)
+		return DocumentResult{DocumentResult: index.DocumentResult{Document: doc}}, ok
+	})
+	return newResult(query, drs, opt, func() ([]string, error) {
+		return suggest(query, idx, params)
+	})
+}
+
+// VersionIndex is the search index of a content version.
+type VersionIndex struct {
+	Version string
+	Index   *index.Index
+}
+
+// SearchVersions searches the indexes of multiple content versions for the query. The results for
+// the same document (by ID) in multiple versions are merged into a single document result that has
+// the highest of their scores and lists the versions (in the order of indexes). The document and
+// its section results are from the first of these versions.
+func SearchVersions(query query.Query, indexes []VersionIndex, params query.ScoreParams, opt Options) (*Result, error) {
+	var drs []DocumentResult
+	byID := map[index.DocID]int{} // the index in drs of each document ID
+	for _, vi := range indexes {
+		result0, err := vi.Index.Search(query, params)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "search version %q", vi.Version)
+		}
+		for _, dr := range result0.DocumentResults {
+			if i, ok := byID[dr.ID]; ok {
+				drs[i].Versions = append(drs[i].Versions, vi.Version)
+				drs[i].Score = max(drs[i].Score, dr.Score)
+				continue
+			}
+			byID[dr.ID] = len(drs)
+			drs = append(drs, DocumentResult{DocumentResult: dr, Versions: []string{vi.Version}})
+		}
+	}
+	sort.Slice(drs, func(i, j int) bool {
+		return drs[i].Score > drs[j].Score || (drs[i].Score == drs[j].Score && drs[i].ID < drs[j].ID)
+	})
+	drs = pin(drs, opt.Pinned, func(id index.DocID) (DocumentResult, bool) {
+		for _, vi := range indexes {
+			if doc, ok := vi.Index.Document(id); ok {
+				return DocumentResult{DocumentResult: index.DocumentResult{Document: doc}, Versions: []string{vi.Version}}, true
+			}
+		}
+		return DocumentResult{}, false
+	})
+	return newResult(query, drs, opt, func() ([]string, error) {
+		if len(indexes) == 0 {
+			return nil, nil
+		}
+		return suggest(query, indexes[0].Index, params)
+	})
+}
+
+// pin returns the document results with the pinned documents first (in order). Pinned documents
+// that are not in drs are looked up with lookup (and have no score).
+func pin(drs []DocumentResult, pinned []index.DocID, lookup func(index.DocID) (DocumentResult, bool)) []DocumentResult {
+	if len(pinned) == 0 {
+		return drs
+	}
+	var (
+		result   []DocumentResult
+		isPinned = map[index.DocID]bool{}
+	)
+	for _, id := range pinned {
+		if isPinned[id] {
+			continue
+		}
+		dr, ok := DocumentResult{}, false
+		for _, dr2 := range drs {
+			if dr2.ID == id {
+				dr, ok = dr2, true
+				break
+			}
+		}
+		if !ok {
+			dr, ok = lookup(id)
+		}
+		if ok {
+			dr.Pinned = true
+			result = append(result, dr)
+			isPinned[id] = true
+		}
+	}
+	for _, dr := range drs {
+		if !isPinned[dr.ID] {
+			result = append(result, dr)
+		}
+	}
+	return result
+}
+
+// newResult returns the result with the document results (which must be sorted) that match the
+// options' filters and are in the range given by the options. If there are no document results, it
+// calls suggest to get suggested queries.
+func newResult(query query.Query, drs []DocumentResult, opt Options, suggest func() ([]string, error)) (*Result, error) {
+	drs, facets := filterFacets(drs, opt.Filters)
+	total := len(drs)
+	drs = drs[min(opt.Offset, len(drs)):]
+	hasMore := opt.Limit > 0 && len(drs) > opt.Limit
+	if hasMore {
+		drs = drs[:opt.Limit]
+	}
 
 	result := &Result{
-		DocumentResults: make([]DocumentResult, len(result0.DocumentResults)),
-		Total:           result0.Total,
+		Query:           query,
+		DocumentResults: drs,
+		Total:           total,
+		Offset:          opt.Offset,
+		Limit:           opt.Limit,
+		HasMore:         hasMore,
+		Facets:          facets,
+		Filters:         opt.Filters,
 	}
-	for i, dr := range result0.DocumentResults {
-		srs, err := documentSectionResults(dr.Data, query)
+	for i, dr := range drs {
+		result.DocumentResults[i].SectionResults = documentSectionResults(dr.Data, query)
+	}
+	if result.Total == 0 {
+		var err error
+		result.Suggestions, err = suggest()
 		if err != nil {
-			return nil, errors.WithMessagef(err, "document section results for %q", dr.ID)
-		}
-		result.DocumentResults[i] = DocumentResult{
-			DocumentResult: dr,
-			SectionResults: srs,
+			return nil, err
 		}
 	}
 	return result, nil
@@ -0,0 +1,120 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/docsite/internal/search/index"
+	"github.com/sourcegraph/docsite/internal/search/query"
+)
+
+func TestSearchVersions(t *testing.T) {
+	newIndex := func(docs ...index.Document) *index.Index {
+		idx, err := index.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, doc := range docs {
+			if err := idx.Add(context.Background(), doc); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return idx
+	}
+	indexes := []VersionIndex{
+		{Version: "1.0", Index: newIndex(
+			index.Document{ID: "a.md", URL: "/@1.0/a", Data: []byte("old setting")},
+			index.Document{ID: "b.md", URL: "/@1.0/b", Data: []byte("setting")},
+		)},
+		{Version: "2.0", Index: newIndex(
+			index.Document{ID: "a.md", URL: "/@2.0/a", Data: []byte("new setting")},
+			index.Document{ID: "c.md", URL: "/@2.0/c", Data: []byte("setting setting")},
+		)},
+	}
+
+	type docResult struct {
+		ID       index.DocID
+		URL      string
+		Versions []string
+	}
+	tests := map[string][]docResult{
+		"setting": {
+			{ID: "c.md", URL: "/@2.0/c", Versions: []string{"2.0"}},
+			{ID: "b.md", URL: "/@1.0/b", Versions: []string{"1.0"}},
+			{ID: "a.md", URL: "/@1.0/a", Versions: []string{"1.0", "2.0"}},
+		},
+		"new":  {{ID: "a.md", URL: "/@2.0/a", Versions: []string{"2.0"}}},
+		"old":  {{ID: "a.md", URL: "/@1.0/a", Versions: []string{"1.0"}}},
+		"none": nil,
+	}
+	for queryStr, want := range tests {
+		t.Run(queryStr, func(t *testing.T) {
+			result, err := SearchVersions(query.Parse(queryStr), indexes, query.DefaultScoreParams(), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []docResult
+			for _, dr := range result.DocumentResults {
+				got = append(got, docResult{ID: dr.ID, URL: dr.URL, Versions: dr.Versions})
+				if len(dr.SectionResults) == 0 {
+					t.Errorf("%s: got no section results", dr.ID)
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+			if result.Total != len(want) {
+				t.Errorf("got total %d, want %d", result.Total, len(want))
+			}
+		})
+	}
+}
+
+func TestSearch_pinned(t *testing.T) {
+	idx, err := index.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range []index.Document{
+		{ID: "a.md", URL: "/a", Data: []byte("install install")},
+		{ID: "b.md", URL: "/b", Data: []byte("install")},
+		{ID: "c.md", URL: "/c", Data: []byte("other")},
+	} {
+		if err := idx.Add(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type docResult struct {
+		ID     index.DocID
+		Pinned bool
+	}
+	tests := map[string]struct {
+		pinned []index.DocID
+		want   []docResult
+	}{
+		"none":         {want: []docResult{{ID: "a.md"}, {ID: "b.md"}}},
+		"matching":     {pinned: []index.DocID{"b.md"}, want: []docResult{{ID: "b.md", Pinned: true}, {ID: "a.md"}}},
+		"not matching": {pinned: []index.DocID{"c.md", "b.md"}, want: []docResult{{ID: "c.md", Pinned: true}, {ID: "b.md", Pinned: true}, {ID: "a.md"}}},
+		"not found":    {pinned: []index.DocID{"x.md"}, want: []docResult{{ID: "a.md"}, {ID: "b.md"}}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := Search(query.Parse("install"), idx, query.DefaultScoreParams(), Options{Pinned: test.pinned})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []docResult
+			for _, dr := range result.DocumentResults {
+				got = append(got, docResult{ID: dr.ID, Pinned: dr.Pinned})
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+			if result.Total != len(test.want) {
+				t.Errorf("got total %d, want %d", result.Total, len(test.want))
+			}
+		})
+	}
+}
@@ -1,11 +1,6 @@
 package search
 
 import (
-	gohtml "html"
-
-	"github.com/yuin/goldmark/ast"
-	"github.com/yuin/goldmark/text"
-
 	"github.com/sourcegraph/docsite/internal/search/query"
 	"github.com/sourcegraph/docsite/markdown"
 )
@@ -16,106 +11,63 @@ type SectionResult struct {
 	Title      string   // the section title
 	TitleStack []string // the stack of section titles
 	Excerpts   []string // the match excerpt
-}
 
-func documentSectionResults(source []byte, query query.Query) ([]SectionResult, error) {
-	type stackEntry struct {
-		id    string
-		title string
-		level int
-	}
-	stack := []stackEntry{{}}
-	cur := func() stackEntry { return stack[len(stack)-1] }
-	root := markdown.New(markdown.Options{}).Parser().Parse(text.NewReader(source))
-
-	var results []SectionResult
-	addResult := func(excerpts []string) {
-		stackIDs := make([]string, len(stack))
-		stackTitles := make([]string, len(stack))
-		for i, e := range stack {
-			stackIDs[i] = e.id
-			stackTitles[i] = e.title
-		}
-		e := cur()
+	// ExcerptInfos describes each excerpt in Excerpts (at the same index), such as whether it is
+	// from a code block.
+	ExcerptInfos []ExcerptInfo
+}
 
-		// If last section result was in the same section, just append the excerpt instead of
-		// creating a new section result.
-		if len(results) > 0 {
-			last := results[len(results)-1]
-			if lastResultIsSameSection := last.ID == e.id; lastResultIsSameSection {
-				last.Excerpts = append(last.Excerpts, excerpts...)
-				return
-			}
-		}
-		results = append(results, SectionResult{
-			ID:         e.id,
-			IDStack:    stackIDs,
-			Title:      e.title,
-			TitleStack: stackTitles,
-			Excerpts:   excerpts,
-		})
-	}
+// ExcerptInfo describes the text that an excerpt is from.
+type ExcerptInfo struct {
+	Code bool   // whether the excerpt is from a code block
+	Lang string // the language of the code block (from its info string), if any
+}
 
-	err := ast.Walk(root, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
-		if !entering {
-			return ast.WalkContinue, nil
-		}
+// documentSectionResults returns the sections of the document's Markdown text that match the
+// query, with excerpts of the matches in the sections' plain text (see markdown.ExtractText).
+func documentSectionResults(source []byte, q query.Query) []SectionResult {
+	sections := markdown.ExtractText(source)
 
-		if node.Kind() == ast.KindHeading {
-			n := node.(*ast.Heading)
-			for n.Level <= cur().level {
-				stack = stack[:len(stack)-1]
+	var results []SectionResult
+	for i, section := range sections {
+		var (
+			excerpts     []string
+			excerptInfos []ExcerptInfo
+		)
+		for _, block := range section.Blocks {
+			field := query.FieldBody
+			if block.Code {
+				field = query.FieldCode
 			}
-
-			// For the document top title heading, use the empty ID.
-			var id string
-			if !markdown.IsDocumentTopTitleHeadingNode(node) {
-				id = markdown.GetAttributeID(n)
+			for _, match := range q.FindAllIndex(block.Text, field) {
+				const excerptMaxLength = 220
+				excerpts = append(excerpts, string(excerpt([]byte(block.Text), match[0], match[1], excerptMaxLength)))
+				excerptInfos = append(excerptInfos, ExcerptInfo{Code: block.Code, Lang: block.Lang})
 			}
-
-			stack = append(stack, stackEntry{
-				id:    id,
-				title: string(n.Text(source)),
-				level: n.Level,
-			})
 		}
 
-		if entering &&
-			(node.Kind() == ast.KindParagraph ||
-				node.Kind() == ast.KindListItem ||
-				node.Kind() == ast.KindHeading ||
-				node.Kind() == ast.KindBlockquote ||
-				node.Kind() == ast.KindCodeBlock ||
-				node.Kind() == ast.KindFencedCodeBlock) {
-			text := node.Text(source)
-			if matches := query.FindAllIndex(string(text)); len(matches) > 0 {
-				// Don't include excerpts for heading because all of the heading is considered the
-				// match.
-				var excerpts []string
-				if node.Kind() != ast.KindHeading {
-					excerpts = make([]string, len(matches))
-					for i, match := range matches {
-						const excerptMaxLength = 220
-						excerpts[i] = gohtml.UnescapeString(string(excerpt(text, match[0], match[1], excerptMaxLength)))
-					}
-				}
-
-				// Remove the previous heading-only match for the same section, if any. A match with
-				// an excerpt is strictly better than one without.
-				if len(results) > 0 {
-					last := results[len(results)-1]
-					if last.ID == cur().id && len(last.Excerpts) == 0 {
-						results = results[:len(results)-1]
-					}
-				}
-
-				addResult(excerpts)
+		// Include a section whose heading matches even without excerpts, because all of the heading
+		// is considered the match.
+		if len(excerpts) == 0 && len(q.FindAllIndex(section.Title, query.FieldHeading)) == 0 {
+			continue
+		}
 
-				return ast.WalkSkipChildren, nil
-			}
+		// The stacks start with an empty entry for the document.
+		idStack := []string{""}
+		titleStack := []string{""}
+		for _, s := range markdown.SectionPath(sections, i) {
+			idStack = append(idStack, s.ID)
+			titleStack = append(titleStack, s.Title)
 		}
+		results = append(results, SectionResult{
+			ID:         section.ID,
+			IDStack:    idStack,
+			Title:      section.Title,
+			TitleStack: titleStack,
+			Excerpts:   excerpts,
 
-		return ast.WalkContinue, nil
-	})
-	return results, err
+			ExcerptInfos: excerptInfos,
+		})
+	}
+	return results
 }
@@ -34,6 +34,11 @@ bb zz`,
 				"b":  {"#b"},
 				"bb": {"#b"},
 				"zz": {"#", "#b"},
+
+				// Qualified terms only match in their fields.
+				"title:bb":   {},
+				"code:zz":    {},
+				"b title:zz": {"#b"},
 			},
 		},
 	}
@@ -41,10 +46,7 @@ bb zz`,
 		t.Run(name, func(t *testing.T) {
 			for queryStr, wantResults := range test.wantQueryResults {
 				t.Run(queryStr, func(t *testing.T) {
-					results, err := documentSectionResults([]byte(test.data), query.Parse(queryStr))
-					if err != nil {
-						t.Fatal(err)
-					}
+					results := documentSectionResults([]byte(test.data), query.Parse(queryStr))
 					if gotResults := toResultsList(results); !reflect.DeepEqual(gotResults, wantResults) {
 						t.Errorf("got results %v, want %v", gotResults, wantResults)
 					}
@@ -53,3 +55,29 @@ bb zz`,
 		})
 	}
 }
+
+func TestDocumentSectionResults_code(t *testing.T) {
+	data := "# A\n\nSet `--max-workers` here.\n\n```sh\ndocsite serve --max-workers=4\n```\n"
+	results := documentSectionResults([]byte(data), query.Parse("--max-workers"))
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	want := []ExcerptInfo{{}, {Code: true, Lang: "sh"}}
+	if got := results[0].ExcerptInfos; !reflect.DeepEqual(got, want) {
+		t.Errorf("got excerpt infos %+v, want %+v", got, want)
+	}
+	if got, want := len(results[0].Excerpts), len(want); got != want {
+		t.Errorf("got %d excerpts, want %d", got, want)
+	}
+}
+
+func TestDocumentSectionResults_codeQualifier(t *testing.T) {
+	data := "# A\n\nThe serve command.\n\n```sh\ndocsite serve\n```\n"
+	results := documentSectionResults([]byte(data), query.Parse("code:serve"))
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	if want := []string{"docsite serve"}; !reflect.DeepEqual(results[0].Excerpts, want) {
+		t.Errorf("got excerpts %q, want %q", results[0].Excerpts, want)
+	}
+}
@@ -0,0 +1,52 @@
+package search
+
+import (
+	"github.com/sourcegraph/docsite/internal/search/index"
+	"github.com/sourcegraph/docsite/internal/search/query"
+)
+
+// maxSuggestions is the maximum number of suggested queries for a query without results.
+const maxSuggestions = 3
+
+// suggest returns queries (such as "authentication" for "authentcation") that correct the
+// spelling of the query's words that are not in the index and that have results.
+func suggest(q query.Query, idx *index.Index, params query.ScoreParams) ([]string, error) {
+	words := map[string][]string{} // similar words for each misspelled term
+	respell := func(k int) func(term string) string {
+		return func(term string) string {
+			if idx.HasTerm(term) {
+				return ""
+			}
+			similar, ok := words[term]
+			if !ok {
+				similar = idx.Suggest(term, maxSuggestions)
+				words[term] = similar
+			}
+			if len(similar) == 0 {
+				return ""
+			}
+			return similar[min(k, len(similar)-1)]
+		}
+	}
+
+	var suggestions []string
+	seen := map[string]struct{}{}
+	for k := 0; k < maxSuggestions; k++ {
+		suggestion := q.Respell(respell(k))
+		if _, ok := seen[suggestion]; ok {
+			continue
+		}
+		seen[suggestion] = struct{}{}
+		if suggestion == q.String() {
+			continue
+		}
+		result, err := idx.Search(query.Parse(suggestion), params)
+		if err != nil {
+			return nil, err
+		}
+		if result.Total > 0 {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions, nil
+}
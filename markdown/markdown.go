@@ -141,6 +141,27 @@ func Run(source []byte, opts Options) (doc *Document, err error) {
 	}, nil
 }
 
+// ParseHeader parses the Markdown document's metadata and title, without HTML-rendering it (which
+// is much slower). The returned document has no HTML or Tree.
+func ParseHeader(source []byte) (doc *Document, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = errors.Errorf("panic while parsing Markdown: %s", e)
+		}
+	}()
+
+	meta, source, err := parseMetadata(source)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse metadata")
+	}
+	title := meta.Title
+	if title == "" {
+		mdAST := New(Options{}).Parser().Parse(text.NewReader(source))
+		title = GetTitle(mdAST, source)
+	}
+	return &Document{Meta: meta, Title: title}, nil
+}
+
 var datePattern = regexp.MustCompile(
 	// For examples, see:
 	// https://developer.mozilla.org/en-US/docs/Web/HTML/Element/time#valid_datetime_values
@@ -58,6 +58,28 @@ title: Metadata title
 	})
 }
 
+func TestParseHeader(t *testing.T) {
+	t.Run("title from heading", func(t *testing.T) {
+		doc, err := ParseHeader([]byte("---\nweight: 2\n---\n\n# My title\n\nHello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, *doc, Document{Meta: Metadata{Weight: 2}, Title: "My title"})
+	})
+	t.Run("title from metadata", func(t *testing.T) {
+		doc, err := ParseHeader([]byte("---\ntitle: Metadata title\n---\n\n# Markdown title"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, *doc, Document{Meta: Metadata{Title: "Metadata title"}, Title: "Metadata title"})
+	})
+	t.Run("invalid metadata", func(t *testing.T) {
+		if _, err := ParseHeader([]byte("---\nweight: x\n---\n")); err == nil {
+			t.Error("got nil error, want error")
+		}
+	})
+}
+
 func TestRenderer(t *testing.T) {
 	t.Run("table with `|`", func(t *testing.T) {
 		doc, err := Run([]byte("a  |  b\n---|---\nc  | `\\|`"), Options{})
@@ -16,6 +16,17 @@ type Metadata struct {
 	ImageURL    string   `yaml:"imageURL"`
 	Type        string   `yaml:"type"`
 	Tags        []string `yaml:"tags"`
+
+	SearchKeywords []string `yaml:"searchKeywords"` // additional words and phrases that match the document in search
+	SearchBoost    float64  `yaml:"searchBoost"`    // the factor to multiply the document's search scores by (1 if 0)
+	SearchExclude  bool     `yaml:"searchExclude"`  // whether to omit the document from search results
+
+	Weight   int `yaml:"weight"`   // the order of the document in the navigation tree (lower weights first)
+	NavOrder int `yaml:"navOrder"` // an alias of Weight (used if Weight is 0)
+
+	Date        string `yaml:"date"`        // the date (YYYY-MM-DD) or time (RFC 3339) that the document was published
+	LastUpdated string `yaml:"lastUpdated"` // the date (YYYY-MM-DD) or time (RFC 3339) that the document was last updated
+	NoIndex     bool   `yaml:"noindex"`     // whether to ask search engines not to index the document
 }
 
 func parseMetadata(input []byte) (meta Metadata, markdown []byte, err error) {
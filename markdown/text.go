@@ -0,0 +1,254 @@
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// TextSection is a section of a Markdown document as plain text. Each section starts at a heading,
+// except for the first section, which consists of the content before the first heading (other than
+// the document top title heading).
+type TextSection struct {
+	ID     string      // the heading ID (the URL fragment without "#"), or empty for the first section
+	Title  string      // the heading text (for the first section, the document top title, if any)
+	Level  int         // the heading level (for the first section, 0 if there is no document top title)
+	Parent int         // the index of the enclosing section (with a lower level), or -1 if none
+	Blocks []TextBlock // the blocks of text in the section, excluding its heading
+}
+
+// TextBlock is a block of plain text, such as a paragraph, list item, or code block.
+type TextBlock struct {
+	Text      string
+	Code      bool     // whether the block is a code block
+	Lang      string   // the language of a fenced code block (the first word of its info string)
+	CodeSpans [][2]int // the byte ranges in Text of inline code spans (such as `foo`)
+}
+
+// ExtractText returns the plain text of a Markdown document (with optional metadata in the
+// Markdown "front matter", which is omitted), split into sections at headings. Links are replaced
+// by their text, and HTML tags are removed (keeping the text inside them). Heading IDs are the same
+// as those of the HTML rendered by Run.
+func ExtractText(source []byte) []TextSection {
+	_, source, _ = parseMetadata(source)
+	ctx := parser.NewContext(
+		func(cfg *parser.ContextConfig) {
+			cfg.IDs = setHeadingIDs()
+		},
+	)
+	root := New(Options{}).Parser().Parse(text.NewReader(source), parser.WithContext(ctx))
+
+	sections := []TextSection{{Parent: -1}}
+	stack := []int{-1} // the indexes of the enclosing sections of the current section
+	level := func(section int) int {
+		if section == -1 {
+			return 0
+		}
+		return sections[section].Level
+	}
+	addBlock := func(block TextBlock) {
+		trimmed := strings.TrimSpace(block.Text)
+		if trimmed == "" {
+			return
+		}
+		leading := len(block.Text) - len(strings.TrimLeftFunc(block.Text, unicode.IsSpace))
+		block.Text = trimmed
+		spans := block.CodeSpans
+		block.CodeSpans = nil
+		for _, span := range spans {
+			span = [2]int{max(span[0]-leading, 0), min(span[1]-leading, len(trimmed))}
+			if span[0] < span[1] {
+				block.CodeSpans = append(block.CodeSpans, span)
+			}
+		}
+		cur := &sections[len(sections)-1]
+		cur.Blocks = append(cur.Blocks, block)
+	}
+
+	_ = ast.Walk(root, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || node.Type() != ast.TypeBlock {
+			return ast.WalkContinue, nil
+		}
+
+		switch n := node.(type) {
+		case *ast.Heading:
+			title, _ := inlineText(n, source)
+			title = strings.TrimSpace(title)
+			if IsDocumentTopTitleHeadingNode(n) {
+				sections[0].Title = title
+				sections[0].Level = n.Level
+				stack = append(stack, 0)
+				return ast.WalkSkipChildren, nil
+			}
+			for level(stack[len(stack)-1]) >= n.Level {
+				stack = stack[:len(stack)-1]
+			}
+			sections = append(sections, TextSection{
+				ID:     GetAttributeID(n),
+				Title:  title,
+				Level:  n.Level,
+				Parent: stack[len(stack)-1],
+			})
+			stack = append(stack, len(sections)-1)
+			return ast.WalkSkipChildren, nil
+
+		case *ast.CodeBlock:
+			addBlock(TextBlock{Text: string(linesValue(n, source)), Code: true})
+			return ast.WalkSkipChildren, nil
+
+		case *ast.FencedCodeBlock:
+			addBlock(TextBlock{Text: string(linesValue(n, source)), Code: true, Lang: string(n.Language(source))})
+			return ast.WalkSkipChildren, nil
+
+		case *ast.HTMLBlock:
+			data := linesValue(n, source)
+			if n.HasClosure() {
+				data = append(data, n.ClosureLine.Value(source)...)
+			}
+			for _, text := range htmlText(data) {
+				addBlock(TextBlock{Text: text})
+			}
+			return ast.WalkSkipChildren, nil
+		}
+
+		// Blocks whose children are inline (such as paragraphs and table cells) are leaf blocks.
+		if child := node.FirstChild(); child != nil && child.Type() == ast.TypeInline {
+			text, codeSpans := inlineText(node, source)
+			addBlock(TextBlock{Text: text, CodeSpans: codeSpans})
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return sections
+}
+
+// SectionPath returns the sections with headings that enclose the section at index i of sections
+// (as returned by ExtractText), outermost first, and the section itself (if it has a heading).
+func SectionPath(sections []TextSection, i int) []TextSection {
+	var path []TextSection
+	for j := i; j != -1; j = sections[j].Parent {
+		if sections[j].Level > 0 {
+			path = append(path, sections[j])
+		}
+	}
+	for j, k := 0, len(path)-1; j < k; j, k = j+1, k-1 {
+		path[j], path[k] = path[k], path[j]
+	}
+	return path
+}
+
+// linesValue returns the source text of the lines of a block node.
+func linesValue(node ast.Node, source []byte) []byte {
+	var buf bytes.Buffer
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		buf.Write(seg.Value(source))
+	}
+	return buf.Bytes()
+}
+
+// inlineText returns the plain text of the inline children of the node, and the byte ranges in
+// the text of code spans. Line breaks are replaced by spaces.
+func inlineText(node ast.Node, source []byte) (string, [][2]int) {
+	var (
+		b         strings.Builder
+		text      strings.Builder // text that is not in a code span (HTML entities are unescaped)
+		spans     [][2]int
+		spanStart int
+	)
+	flush := func() {
+		b.WriteString(html.UnescapeString(text.String()))
+		text.Reset()
+	}
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if _, ok := n.(*ast.CodeSpan); ok {
+			if entering {
+				flush()
+				spanStart = b.Len()
+			} else if b.Len() > spanStart {
+				spans = append(spans, [2]int{spanStart, b.Len()})
+			}
+			return ast.WalkContinue, nil
+		}
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		w := &text
+		if _, ok := n.Parent().(*ast.CodeSpan); ok {
+			w = &b // code spans are literal
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			w.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				w.WriteByte(' ')
+			}
+		case *ast.String:
+			w.Write(n.Value)
+		case *ast.AutoLink:
+			w.Write(n.Label(source))
+		case *ast.RawHTML:
+			return ast.WalkSkipChildren, nil // omit HTML tags (but not the text between them)
+		}
+		return ast.WalkContinue, nil
+	})
+	flush()
+	return b.String(), spans
+}
+
+// htmlText returns the text of each block-level element of an HTML fragment (without tags).
+func htmlText(htmlFragment []byte) []string {
+	var (
+		texts []string
+		b     strings.Builder
+		skip  int // the depth of elements whose text is omitted (such as <script>)
+	)
+	endBlock := func() {
+		if s := strings.Join(strings.Fields(b.String()), " "); s != "" {
+			texts = append(texts, s)
+		}
+		b.Reset()
+	}
+
+	z := nethtml.NewTokenizer(bytes.NewReader(htmlFragment))
+	for {
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			// The tokenizer keeps returning the same error (io.EOF at the end of the fragment) after
+			// the first one, so stop on any error.
+			break
+		}
+		tok := z.Token()
+		switch tok.Type {
+		case nethtml.TextToken:
+			if skip == 0 {
+				b.WriteString(tok.Data)
+			}
+		case nethtml.StartTagToken, nethtml.EndTagToken, nethtml.SelfClosingTagToken:
+			switch tok.DataAtom {
+			case atom.Script, atom.Style, atom.Template:
+				if tok.Type == nethtml.StartTagToken {
+					skip++
+				} else if tok.Type == nethtml.EndTagToken && skip > 0 {
+					skip--
+				}
+			case atom.Address, atom.Article, atom.Aside, atom.Blockquote, atom.Br, atom.Dd, atom.Details,
+				atom.Div, atom.Dl, atom.Dt, atom.Figcaption, atom.Figure, atom.Footer, atom.H1, atom.H2,
+				atom.H3, atom.H4, atom.H5, atom.H6, atom.Header, atom.Hr, atom.Li, atom.Main, atom.Nav,
+				atom.Ol, atom.P, atom.Pre, atom.Section, atom.Summary, atom.Table, atom.Td, atom.Th,
+				atom.Tr, atom.Ul:
+				endBlock()
+			}
+		}
+	}
+	endBlock()
+	return texts
+}
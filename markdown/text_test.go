@@ -0,0 +1,43 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractText(t *testing.T) {
+	tests := map[string][]TextSection{
+		"": {{Parent: -1}},
+		"---\ntitle: T\ntags: [a]\n---\n# Title\n\nIntro with a [link](../foo.md) and **bold** &amp; `code`.": {
+			{Title: "Title", Level: 1, Parent: -1, Blocks: []TextBlock{{Text: "Intro with a link and bold & code.", CodeSpans: [][2]int{{29, 33}}}}},
+		},
+		"a\nb\n\n## B\n\n- x\n- y <span class=\"z\">z</span>\n\n### C\n\n```go\nfunc f()\n```\n\n## D": {
+			{Parent: -1, Blocks: []TextBlock{{Text: "a b"}}},
+			{ID: "b", Title: "B", Level: 2, Parent: -1, Blocks: []TextBlock{{Text: "x"}, {Text: "y z"}}},
+			{ID: "c", Title: "C", Level: 3, Parent: 1, Blocks: []TextBlock{{Text: "func f()", Code: true, Lang: "go"}}},
+			{ID: "d", Title: "D", Level: 2, Parent: -1},
+		},
+		"  `--max-workers` and `a &amp; b`": {
+			{Parent: -1, Blocks: []TextBlock{{Text: "--max-workers and a &amp; b", CodeSpans: [][2]int{{0, 13}, {18, 27}}}}},
+		},
+		"# T\n\n## [Link](http://example.com) heading\n\n## Café\n\n## Café": {
+			{Title: "T", Level: 1, Parent: -1},
+			{ID: "link-http-example-com-heading", Title: "Link heading", Level: 2, Parent: 0},
+			{ID: "café", Title: "Café", Level: 2, Parent: 0},
+			{ID: "café-1", Title: "Café", Level: 2, Parent: 0},
+		},
+		"<div class=\"x\">\n<p>Generated &lt;text&gt;</p><script>var x;</script>\n<ul><li>One</li><li>Two</li></ul>\n</div>": {
+			{Parent: -1, Blocks: []TextBlock{{Text: "Generated <text>"}, {Text: "One"}, {Text: "Two"}}},
+		},
+		"| A | B |\n|---|---|\n| <https://x.example> | b |": {
+			{Parent: -1, Blocks: []TextBlock{{Text: "A"}, {Text: "B"}, {Text: "https://x.example"}, {Text: "b"}}},
+		},
+	}
+	for source, want := range tests {
+		t.Run(source, func(t *testing.T) {
+			if got := ExtractText([]byte(source)); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
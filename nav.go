@@ -0,0 +1,279 @@
+package docsite
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	pathpkg "path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// navFileName is the name of the file in a content directory that lists the order of the
+// directory's entries in the navigation tree.
+const navFileName = "_nav.yml"
+
+// NavNode is a page or directory in the navigation tree of the site's content. The root node is the
+// content root (and its index page, if any).
+type NavNode struct {
+	Title    string     // the page title, or the directory name if the directory has no index page
+	Path     string     // the canonical URL path of the page or directory (see ContentPage.Path)
+	URL      string     // the URL of the page, or empty if it is a directory with no index page
+	Weight   int        // the weight of the page (from its metadata), which orders it among its siblings
+	Active   bool       // whether the node is the current page
+	Expanded bool       // whether the node or any of its descendants is the current page
+	Children []*NavNode // the pages and directories in the directory (if the node is a directory)
+}
+
+// navTreeCacheEntry is a navigation tree built from a version of the content.
+type navTreeCacheEntry struct {
+	content http.FileSystem // the content file system that the tree was built from
+	stamp   string          // the stamp of the content when the tree was built
+	tree    *NavNode
+}
+
+// NavTree returns the navigation tree of the content version, with the page at activePath (a
+// canonical URL path, see ContentPage.Path) marked as active.
+//
+// The tree contains all content pages, titled by their document title. Each directory's entries
+// are ordered first by the directory's _nav.yml file (a YAML list of the entries' names, such as
+// "install" for install.md or the install directory), then by the pages' weight (from the "weight"
+// or "navOrder" metadata), and then by title.
+func (s *Site) NavTree(ctx context.Context, contentVersion, activePath string) (*NavNode, error) {
+	tree, err := s.navTree(ctx, contentVersion)
+	if err != nil {
+		return nil, err
+	}
+	return tree.withActive(strings.Trim(activePath, "/")), nil
+}
+
+// navTree returns the navigation tree of the content version (with no active page). The tree is
+// built once and reused until the version's file system is replaced or its files change (see
+// contentStamp). It must not be modified.
+func (s *Site) navTree(ctx context.Context, contentVersion string) (*NavNode, error) {
+	content, err := s.Content.OpenVersion(ctx, contentVersion)
+	if err != nil {
+		return nil, err
+	}
+	stamp, err := s.contentStamp(contentVersion, content)
+	if err != nil {
+		return nil, err
+	}
+
+	s.navTreesMu.Lock()
+	e := s.navTrees[contentVersion]
+	s.navTreesMu.Unlock()
+	if e != nil && sameFileSystem(e.content, content) && e.stamp == stamp {
+		return e.tree, nil
+	}
+
+	tree, err := s.buildNavTree(ctx, content, contentVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	s.navTreesMu.Lock()
+	if s.navTrees == nil {
+		s.navTrees = map[string]*navTreeCacheEntry{}
+	}
+	s.navTrees[contentVersion] = &navTreeCacheEntry{content: content, stamp: stamp, tree: tree}
+	s.navTreesMu.Unlock()
+	return tree, nil
+}
+
+// buildNavTree builds the navigation tree of all content pages in the file system. Only the pages'
+// metadata and titles are read (the pages are not rendered), and pages with invalid metadata are
+// omitted.
+func (s *Site) buildNavTree(ctx context.Context, content http.FileSystem, contentVersion string) (*NavNode, error) {
+	paths, err := s.contentPagePaths(content)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := s.contentPageHeaders(ctx, content, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	base := s.Base
+	if base == nil {
+		base = &url.URL{Path: "/"}
+	}
+
+	// Create the node of each directory (in which the index page, if any, is filled in) and page.
+	dirs := map[string]*NavNode{"": {}}
+	var dirNode func(dir string) *NavNode
+	dirNode = func(dir string) *NavNode {
+		if n, ok := dirs[dir]; ok {
+			return n
+		}
+		n := &NavNode{Title: pathpkg.Base(dir), Path: dir}
+		dirs[dir] = n
+		parent := dirNode(parentDir(dir))
+		parent.Children = append(parent.Children, n)
+		return n
+	}
+	for _, page := range pages {
+		var n *NavNode
+		if pathpkg.Base(page.FilePath) == "index.md" {
+			n = dirNode(page.Path)
+		} else {
+			n = &NavNode{Path: page.Path}
+			parent := dirNode(parentDir(page.Path))
+			parent.Children = append(parent.Children, n)
+		}
+		n.Title = page.Doc.Title
+		if n.Title == "" && page.Path != "" {
+			n.Title = pathpkg.Base(page.Path)
+		}
+		n.URL = base.ResolveReference(&url.URL{Path: versionedPath(contentVersion, page.Path)}).String()
+		n.Weight = page.Doc.Meta.Weight
+		if n.Weight == 0 {
+			n.Weight = page.Doc.Meta.NavOrder
+		}
+	}
+
+	for dir, n := range dirs {
+		order, err := readNavFile(content, dir)
+		if err != nil {
+			return nil, err
+		}
+		sortNavNodes(n.Children, order)
+	}
+	return dirs[""], nil
+}
+
+// readNavFile reads the _nav.yml file of the directory, if any, and returns the position of each
+// entry name that it lists.
+func readNavFile(content http.FileSystem, dir string) (map[string]int, error) {
+	path := pathpkg.Join("/", dir, navFileName)
+	data, err := ReadFile(content, path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	if err := yaml.UnmarshalStrict(data, &names); err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("parse %s", path))
+	}
+	order := make(map[string]int, len(names))
+	for i, name := range names {
+		name = strings.TrimSuffix(strings.Trim(name, "/"), ".md")
+		if _, seen := order[name]; !seen {
+			order[name] = i
+		}
+	}
+	return order, nil
+}
+
+// sortNavNodes sorts sibling nodes by their position in order (if listed), then by weight, and then
+// by title.
+func sortNavNodes(nodes []*NavNode, order map[string]int) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		oi, listedI := order[pathpkg.Base(nodes[i].Path)]
+		oj, listedJ := order[pathpkg.Base(nodes[j].Path)]
+		if listedI != listedJ {
+			return listedI
+		}
+		if listedI && oi != oj {
+			return oi < oj
+		}
+		if nodes[i].Weight != nodes[j].Weight {
+			return nodes[i].Weight < nodes[j].Weight
+		}
+		if ti, tj := strings.ToLower(nodes[i].Title), strings.ToLower(nodes[j].Title); ti != tj {
+			return ti < tj
+		}
+		return nodes[i].Path < nodes[j].Path
+	})
+}
+
+// withActive returns the tree with the page at path marked as active (and it and its ancestors
+// marked as expanded). Only the nodes from the root to the active page are copied; the rest of the
+// tree is shared with n. If no page is at path, n itself is returned.
+func (n *NavNode) withActive(path string) *NavNode {
+	if n.URL != "" && n.Path == path {
+		c := *n
+		c.Active, c.Expanded = true, true
+		return &c
+	}
+	for i, child := range n.Children {
+		if child.Path != path && !strings.HasPrefix(path, child.Path+"/") {
+			continue
+		}
+		active := child.withActive(path)
+		if active == child {
+			continue // the page is not in the child's subtree
+		}
+		c := *n
+		c.Expanded = true
+		c.Children = append([]*NavNode(nil), n.Children...)
+		c.Children[i] = active
+		return &c
+	}
+	return n
+}
+
+// setNavLinks sets the page's breadcrumbs and links to the pages around it in the navigation tree
+// (which must have the page marked as active). They are not set if the page is not in the tree.
+func (p *ContentPage) setNavLinks(tree *NavNode) {
+	nodes := tree.activePath()
+	p.Breadcrumbs = makeBreadcrumbEntries(nodes)
+	if len(nodes) < 2 {
+		return
+	}
+	node, parent := nodes[len(nodes)-1], nodes[len(nodes)-2]
+
+	link := func(n *NavNode) *PageLink {
+		return &PageLink{Title: n.Title, URL: n.URL, Active: n.Active}
+	}
+	if parent.URL != "" {
+		p.Parent = link(parent)
+	}
+	var prev *NavNode
+	for _, n := range parent.Children {
+		if n.URL == "" {
+			continue // a directory without an index page
+		}
+		if n == node {
+			if prev != nil {
+				p.Prev = link(prev)
+			}
+		} else if prev == node {
+			p.Next = link(n)
+		}
+		p.Siblings = append(p.Siblings, *link(n))
+		prev = n
+	}
+}
+
+// activePath returns the nodes from the root of the tree to the active node, or nil if there is
+// no active node.
+func (n *NavNode) activePath() []*NavNode {
+	if n.Active {
+		return []*NavNode{n}
+	}
+	if !n.Expanded {
+		return nil
+	}
+	for _, c := range n.Children {
+		if nodes := c.activePath(); nodes != nil {
+			return append([]*NavNode{n}, nodes...)
+		}
+	}
+	return nil
+}
+
+// parentDir returns the parent directory of a canonical URL path (or empty for the content root).
+func parentDir(path string) string {
+	if dir := pathpkg.Dir(path); dir != "." {
+		return dir
+	}
+	return ""
+}
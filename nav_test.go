@@ -0,0 +1,284 @@
+package docsite
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/godoc/vfs/httpfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func TestSite_NavTree(t *testing.T) {
+	ctx := context.Background()
+	site := Site{
+		Content: versionedFileSystem{
+			"": httpfs.New(mapfs.New(map[string]string{
+				"index.md":         "# Home",
+				"b.md":             "# Beta",
+				"a.md":             "# Alpha",
+				"heavy.md":         "---\nweight: 10\n---\n# Aardvark",
+				"light.md":         "---\nnavOrder: -1\n---\n# Zulu",
+				"_nav.yml":         "- z\n- admin/\n",
+				"z.md":             "# Zeta",
+				"admin/index.md":   "# Administration",
+				"admin/install.md": "# Install",
+				"admin/config.md":  "# Configure",
+				"admin/_nav.yml":   "- install.md\n",
+				"dev/setup.md":     "# Setup",
+			})),
+			"other": httpfs.New(mapfs.New(map[string]string{"a.md": "# A"})),
+		},
+		Base: &url.URL{Path: "/help/"},
+	}
+
+	// toList returns each node in the tree (depth-first) as "path title url", with "*" if it is
+	// active and "+" if it is expanded.
+	toList := func(tree *NavNode) []string {
+		var l []string
+		var walk func(n *NavNode, depth int)
+		walk = func(n *NavNode, depth int) {
+			entry := fmt.Sprintf("%s%s %s %s", strings.Repeat(" ", depth), n.Path, n.Title, n.URL)
+			if n.Active {
+				entry += " *"
+			}
+			if n.Expanded {
+				entry += " +"
+			}
+			l = append(l, entry)
+			for _, c := range n.Children {
+				walk(c, depth+1)
+			}
+		}
+		walk(tree, 0)
+		return l
+	}
+
+	tree, err := site.NavTree(ctx, "", "admin/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		" Home /help/ +",
+		" z Zeta /help/z",
+		" admin Administration /help/admin +",
+		"  admin/install Install /help/admin/install",
+		"  admin/config Configure /help/admin/config * +",
+		" light Zulu /help/light",
+		" a Alpha /help/a",
+		" b Beta /help/b",
+		" dev dev ",
+		"  dev/setup Setup /help/dev/setup",
+		" heavy Aardvark /help/heavy",
+	}
+	if got := toList(tree); !reflect.DeepEqual(got, want) {
+		t.Errorf("got tree\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	t.Run("active page is not shared", func(t *testing.T) {
+		tree, err := site.NavTree(ctx, "", "/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := toList(tree); got[2] != " admin Administration /help/admin" || got[6] != " a Alpha /help/a * +" {
+			t.Errorf("got tree %q, want only a active", got)
+		}
+	})
+
+	t.Run("only the active path is copied", func(t *testing.T) {
+		cached, err := site.navTree(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree, err := site.NavTree(ctx, "", "admin/config")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tree == cached || tree.Children[1] == cached.Children[1] {
+			t.Error("got active path shared with the cached tree, want it copied")
+		}
+		if tree.Children[0] != cached.Children[0] || tree.Children[1].Children[0] != cached.Children[1].Children[0] {
+			t.Error("got inactive nodes copied, want them shared with the cached tree")
+		}
+		if cached.Expanded || cached.Children[1].Children[1].Active {
+			t.Error("got cached tree modified")
+		}
+	})
+
+	t.Run("directory without index page is not active", func(t *testing.T) {
+		tree, err := site.NavTree(ctx, "", "dev")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range toList(tree) {
+			if strings.HasSuffix(entry, "+") {
+				t.Errorf("got expanded entry %q, want none", entry)
+			}
+		}
+	})
+
+	t.Run("page with invalid metadata", func(t *testing.T) {
+		site := Site{
+			Content: versionedFileSystem{
+				"": httpfs.New(mapfs.New(map[string]string{
+					"a.md": "# A",
+					"b.md": "---\ntitle: [\n---\n# B",
+				})),
+			},
+			Base: &url.URL{Path: "/"},
+		}
+		tree, err := site.NavTree(ctx, "", "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := toList(tree), []string{"   +", " a A /a * +"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got tree %q, want %q", got, want)
+		}
+	})
+
+	t.Run("version", func(t *testing.T) {
+		tree, err := site.NavTree(ctx, "other", "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := toList(tree), []string{"   +", " a A /help/@other/a * +"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got tree %q, want %q", got, want)
+		}
+	})
+}
+
+func TestSite_Handler_navTree(t *testing.T) {
+	site := Site{
+		Content: versionedFileSystem{
+			"": httpfs.New(mapfs.New(map[string]string{
+				"index.md": "# Home",
+				"a.md":     "# A",
+				"b/c.md":   "# C",
+				"d.md":     "# D",
+				"_resources/templates/document.html": `{{define "nav"}}{{range .}}[{{if .Active}}*{{end}}{{.Title}}{{template "nav" .Children}}]{{end}}{{end}}` +
+					`{{template "nav" .NavTree.Children}}|{{with navTree "" "b/c"}}{{template "nav" .Children}}{{end}}` +
+					`|{{with .Content.Next}}{{.Title}} {{.URL}}{{end}}|{{range .Content.Breadcrumbs}}{{.Label}} {{end}}`,
+			})),
+		},
+		Base: &url.URL{Path: "/"},
+	}
+	handler := site.Handler()
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/a", nil)
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got HTTP status %d, want %d: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if got, want := rr.Body.String(), "[*A][b[C]][D]|[A][b[*C]][D]|D /d|Home A "; !strings.Contains(got, want) {
+		t.Errorf("got body %q, want it to contain %q", got, want)
+	}
+}
+
+func TestSite_Handler_navTreeError(t *testing.T) {
+	site := Site{
+		Content: versionedFileSystem{
+			"": httpfs.New(mapfs.New(map[string]string{
+				"a.md":                               "# A",
+				"_nav.yml":                           "{",
+				"_resources/templates/document.html": `{{.Content.Doc.Title}}|{{if .NavTree}}nav{{end}}|{{with .Content.Next}}next{{end}}|{{len .Content.Breadcrumbs}}`,
+			})),
+		},
+		Base: &url.URL{Path: "/"},
+	}
+	handler := site.Handler()
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/a", nil)
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got HTTP status %d, want %d: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if got, want := rr.Body.String(), "A|||0"; !strings.Contains(got, want) {
+		t.Errorf("got body %q, want it to contain %q", got, want)
+	}
+}
+
+func TestContentPage_setNavLinks(t *testing.T) {
+	ctx := context.Background()
+	site := Site{
+		Content: versionedFileSystem{
+			"": httpfs.New(mapfs.New(map[string]string{
+				"index.md":           "# Home",
+				"tutorial/index.md":  "# Tutorial",
+				"tutorial/_nav.yml":  "- start\n- middle\n- end\n",
+				"tutorial/start.md":  "# Start",
+				"tutorial/middle.md": "# Middle",
+				"tutorial/end.md":    "# End",
+				"tutorial/x/y.md":    "# Y",
+			})),
+		},
+		Base: &url.URL{Path: "/"},
+	}
+
+	link := func(title, url string) *PageLink { return &PageLink{Title: title, URL: url} }
+	tests := map[string]struct {
+		wantPrev, wantNext, wantParent *PageLink
+		wantSiblings                   []string
+	}{
+		"tutorial/start": {
+			wantNext:     link("Middle", "/tutorial/middle"),
+			wantParent:   link("Tutorial", "/tutorial"),
+			wantSiblings: []string{"*Start", "Middle", "End"},
+		},
+		"tutorial/middle": {
+			wantPrev:     link("Start", "/tutorial/start"),
+			wantNext:     link("End", "/tutorial/end"),
+			wantParent:   link("Tutorial", "/tutorial"),
+			wantSiblings: []string{"Start", "*Middle", "End"},
+		},
+		"tutorial/end": {
+			wantPrev:     link("Middle", "/tutorial/middle"),
+			wantParent:   link("Tutorial", "/tutorial"),
+			wantSiblings: []string{"Start", "Middle", "*End"},
+		},
+		"tutorial": {
+			wantParent:   link("Home", "/"),
+			wantSiblings: []string{"*Tutorial"},
+		},
+		"tutorial/x/y": {
+			wantSiblings: []string{"*Y"},
+		},
+		"": {},
+	}
+	for path, test := range tests {
+		t.Run(path, func(t *testing.T) {
+			tree, err := site.NavTree(ctx, "", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var page ContentPage
+			page.setNavLinks(tree)
+			if !reflect.DeepEqual(page.Prev, test.wantPrev) {
+				t.Errorf("got prev %+v, want %+v", page.Prev, test.wantPrev)
+			}
+			if !reflect.DeepEqual(page.Next, test.wantNext) {
+				t.Errorf("got next %+v, want %+v", page.Next, test.wantNext)
+			}
+			if !reflect.DeepEqual(page.Parent, test.wantParent) {
+				t.Errorf("got parent %+v, want %+v", page.Parent, test.wantParent)
+			}
+			var siblings []string
+			for _, s := range page.Siblings {
+				if s.Active {
+					siblings = append(siblings, "*"+s.Title)
+				} else {
+					siblings = append(siblings, s.Title)
+				}
+			}
+			if !reflect.DeepEqual(siblings, test.wantSiblings) {
+				t.Errorf("got siblings %v, want %v", siblings, test.wantSiblings)
+			}
+		})
+	}
+}
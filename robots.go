@@ -0,0 +1,79 @@
+package docsite
+
+import (
+	"bytes"
+	"fmt"
+)
+
+const (
+	robotsNoIndex         = "noindex"          // the robots meta tag content for a page that must not be indexed
+	robotsNoIndexNoFollow = "noindex,nofollow" // the robots meta tag content for a version that must not be indexed
+)
+
+// RobotsRule is a group of rules in robots.txt for the crawlers with a user agent.
+type RobotsRule struct {
+	UserAgent string   // the user agent of the crawlers (such as "*" for all crawlers)
+	Allow     []string // URL path prefixes that the crawlers may crawl
+	Disallow  []string // URL path prefixes that the crawlers must not crawl
+}
+
+// RobotsTxt returns the robots.txt file of the site, with its RobotsRules (or, if there are none,
+// a rule that allows all crawlers to crawl everything) and the URL of the sitemap index (if the
+// site has a Root URL and the sitemap index lists any sitemaps).
+//
+// Crawlers only read the robots.txt file at the root of the host, so if Base is not the root, the
+// handler serves it at both Base and the root, and the host's robots.txt must include its rules if
+// other servers serve the root.
+func (s *Site) RobotsTxt() []byte {
+	rules := s.RobotsRules
+	if len(rules) == 0 {
+		rules = []RobotsRule{{UserAgent: "*"}}
+	}
+
+	var buf bytes.Buffer
+	for i, rule := range rules {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "User-agent: %s\n", rule.UserAgent)
+		for _, path := range rule.Allow {
+			fmt.Fprintf(&buf, "Allow: %s\n", path)
+		}
+		for _, path := range rule.Disallow {
+			fmt.Fprintf(&buf, "Disallow: %s\n", path)
+		}
+		if len(rule.Allow) == 0 && len(rule.Disallow) == 0 {
+			buf.WriteString("Disallow:\n") // allow everything
+		}
+	}
+	if s.Root != nil && len(s.sitemapIndexVersions()) > 0 {
+		fmt.Fprintf(&buf, "\nSitemap: %s\n", s.absoluteURL(sitemapIndexFileName))
+	}
+	return buf.Bytes()
+}
+
+// robotsMeta returns the content of the robots meta tag for the page at the content version (or for
+// a page that was not found, if page is nil), or empty if search engines may index the page.
+//
+// Only pages of the IndexedVersions may be indexed, unless they were not found or have "noindex"
+// metadata.
+func (s *Site) robotsMeta(contentVersion string, page *ContentPage) string {
+	switch {
+	case !s.isIndexedVersion(contentVersion):
+		return robotsNoIndexNoFollow
+	case page == nil || page.Doc.Meta.NoIndex:
+		return robotsNoIndex
+	}
+	return ""
+}
+
+// isIndexedVersion reports whether search engines may index the content version's pages (see
+// IndexedVersions).
+func (s *Site) isIndexedVersion(contentVersion string) bool {
+	for _, v := range s.IndexedVersions {
+		if v == contentVersion {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package docsite
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/godoc/vfs/httpfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
+
+	"github.com/sourcegraph/docsite/markdown"
+)
+
+func TestSite_RobotsTxt(t *testing.T) {
+	tests := map[string]struct {
+		site *Site
+		want string
+	}{
+		"default": {
+			site: &Site{},
+			want: "User-agent: *\nDisallow:\n",
+		},
+		"rules and sitemap": {
+			site: &Site{
+				RobotsRules: []RobotsRule{
+					{UserAgent: "*", Allow: []string{"/@main/"}, Disallow: []string{"/@"}},
+					{UserAgent: "BadBot", Disallow: []string{"/"}},
+				},
+				Base:            &url.URL{Path: "/help/"},
+				Root:            &url.URL{Scheme: "https", Host: "example.com"},
+				IndexedVersions: []string{""},
+			},
+			want: `User-agent: *
+Allow: /@main/
+Disallow: /@
+
+User-agent: BadBot
+Disallow: /
+
+Sitemap: https://example.com/help/sitemap_index.xml
+`,
+		},
+		"no indexed versions": {
+			site: &Site{Root: &url.URL{Scheme: "https", Host: "example.com"}},
+			want: "User-agent: *\nDisallow:\n",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(test.want, string(test.site.RobotsTxt())); diff != "" {
+				t.Errorf("robots.txt mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSite_robotsMeta(t *testing.T) {
+	site := Site{IndexedVersions: []string{""}}
+	page := &ContentPage{}
+	noIndexPage := &ContentPage{Doc: markdown.Document{Meta: markdown.Metadata{NoIndex: true}}}
+
+	tests := map[string]struct {
+		contentVersion string
+		page           *ContentPage
+		want           string
+	}{
+		"indexed version":       {page: page, want: ""},
+		"other version":         {contentVersion: "v1", page: page, want: "noindex,nofollow"},
+		"noindex metadata":      {page: noIndexPage, want: "noindex"},
+		"page not found":        {page: nil, want: "noindex"},
+		"other version noindex": {contentVersion: "v1", page: noIndexPage, want: "noindex,nofollow"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := site.robotsMeta(test.contentVersion, test.page); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	t.Run("no indexed versions", func(t *testing.T) {
+		if got, want := (&Site{}).robotsMeta("", page), "noindex,nofollow"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestSite_Handler_robots(t *testing.T) {
+	const legacyDocumentTemplate = `<meta property="og:locale" content="en_EN">{{with .Content}}{{markdown .}}{{end}}`
+	site := Site{
+		Content: versionedFileSystem{
+			"": httpfs.New(mapfs.New(map[string]string{
+				"a.md":                               "a",
+				"b.md":                               "---\nnoindex: true\n---\nb",
+				"_resources/templates/document.html": `{{with .RobotsMeta}}<meta name="robots" content="{{.}}">{{end}}`,
+			})),
+			"v1": httpfs.New(mapfs.New(map[string]string{
+				"a.md":                               "a",
+				"_resources/templates/document.html": legacyDocumentTemplate,
+			})),
+		},
+		Base:            &url.URL{Path: "/"},
+		IndexedVersions: []string{""},
+	}
+	handler := site.Handler()
+
+	get := func(t *testing.T, path string) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("robots.txt", func(t *testing.T) {
+		rr := get(t, "/robots.txt")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got HTTP status %d, want %d", rr.Code, http.StatusOK)
+		}
+		if got, want := rr.Body.String(), "User-agent: *\nDisallow:\n"; got != want {
+			t.Errorf("got body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("robots.txt at root with base URL path", func(t *testing.T) {
+		site := Site{Content: site.Content, Base: &url.URL{Path: "/help/"}}
+		for _, path := range []string{"/robots.txt", "/help/robots.txt"} {
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", path, nil)
+			site.Handler().ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Errorf("%s: got HTTP status %d, want %d", path, rr.Code, http.StatusOK)
+			}
+		}
+	})
+
+	tests := map[string]string{
+		"/a":     "",
+		"/b":     `<meta name="robots" content="noindex">`,
+		"/c":     `<meta name="robots" content="noindex">`,
+		"/@v1/a": `<meta property="og:locale" content="en_EN">` + "\n  " + metaRobots, // legacy template is patched
+	}
+	for path, want := range tests {
+		t.Run(path, func(t *testing.T) {
+			rr := get(t, path)
+			if got := rr.Body.String(); !strings.HasPrefix(got, want) || (want == "" && strings.Contains(got, "robots")) {
+				t.Errorf("got body %q, want prefix %q", got, want)
+			}
+		})
+	}
+}
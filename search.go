@@ -3,12 +3,19 @@ package docsite
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"html"
 	"html/template"
+	"net/http"
 	"net/url"
+	"os"
+	pathpkg "path"
+	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/text"
 
@@ -18,40 +25,372 @@ import (
 	"github.com/sourcegraph/docsite/markdown"
 )
 
-// Search searches all documents at the version for a query.
-func (s *Site) Search(ctx context.Context, contentVersion string, queryStr string) (*search.Result, error) {
-	pages, err := s.AllContentPages(ctx, contentVersion)
+// Search searches all documents at the version for a query. Only the document results in the
+// range given by the options are returned.
+func (s *Site) Search(ctx context.Context, contentVersion string, queryStr string, opt search.Options) (*search.Result, error) {
+	idx, synonyms, err := s.searchIndexAndSynonyms(ctx, contentVersion)
 	if err != nil {
 		return nil, err
 	}
+	if id, ok := s.searchBestBet(queryStr, func(id index.DocID) bool { _, ok := idx.Document(id); return ok }); ok {
+		opt.Pinned = append([]index.DocID{id}, opt.Pinned...)
+	}
+	return search.Search(query.ParseWithSynonyms(queryStr, synonyms), idx, s.searchScoreParams(), opt)
+}
 
-	idx, err := index.New()
+// SearchVersions searches all documents at multiple versions for a query. Document results for the
+// same page at multiple versions are merged (see search.SearchVersions).
+func (s *Site) SearchVersions(ctx context.Context, contentVersions []string, queryStr string, opt search.Options) (*search.Result, error) {
+	indexes := make([]search.VersionIndex, len(contentVersions))
+	versionSynonyms := make([]*query.Synonyms, len(contentVersions))
+	for i, contentVersion := range contentVersions {
+		idx, synonyms, err := s.searchIndexAndSynonyms(ctx, contentVersion)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "search index for version %q", contentVersion)
+		}
+		indexes[i] = search.VersionIndex{Version: contentVersion, Index: idx}
+		versionSynonyms[i] = synonyms
+	}
+	synonyms := query.MergeSynonyms(versionSynonyms...)
+	hasDocument := func(id index.DocID) bool {
+		for _, vi := range indexes {
+			if _, ok := vi.Index.Document(id); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if id, ok := s.searchBestBet(queryStr, hasDocument); ok {
+		opt.Pinned = append([]index.DocID{id}, opt.Pinned...)
+	}
+	return search.SearchVersions(query.ParseWithSynonyms(queryStr, synonyms), indexes, s.searchScoreParams(), opt)
+}
+
+// searchBestBet returns the ID of the document of the best bet for the query (see
+// Site.SearchBestBets), if any. The hasDocument func reports whether a document is in the index.
+func (s *Site) searchBestBet(queryStr string, hasDocument func(index.DocID) bool) (index.DocID, bool) {
+	normalize := func(q string) string { return strings.Join(strings.Fields(strings.ToLower(q)), " ") }
+	queryStr = normalize(queryStr)
+	if queryStr == "" {
+		return "", false
+	}
+	for bestBetQuery, urlPath := range s.SearchBestBets {
+		if normalize(bestBetQuery) != queryStr {
+			continue
+		}
+		// The document ID is the file path, which is either the path with ".md" or the path's
+		// directory index file.
+		path := strings.Trim(urlPath, "/")
+		candidates := []index.DocID{index.DocID(path + ".md"), index.DocID(pathpkg.Join(path, "index.md"))}
+		for _, id := range candidates {
+			if hasDocument(id) {
+				return id, true
+			}
+		}
+	}
+	return "", false
+}
+
+// searchSynonymsFile is the name of the file of synonyms for search queries (in the "search"
+// resources directory). See query.ParseSynonyms for its format.
+const searchSynonymsFile = "synonyms.txt"
+
+// readSearchSynonyms reads the synonyms used to expand search queries from the synonyms file of the
+// content (if any).
+func readSearchSynonyms(content http.FileSystem) (*query.Synonyms, error) {
+	data, err := ReadFile(&subdirFileSystem{fs: content, path: "_resources/search"}, searchSynonymsFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	synonyms, err := query.ParseSynonyms(data)
+	if err != nil {
+		return nil, errors.WithMessage(err, "search synonyms")
+	}
+	return synonyms, nil
+}
+
+// maxSearchVersions is the maximum number of content versions that a search can list (other than
+// with "*").
+const maxSearchVersions = 10
+
+// ParseSearchVersions parses a list of content versions to search, which is either "*" (for
+// all of the site's SearchableVersions) or a comma-separated list of up to maxSearchVersions
+// versions (in which an empty version is the default version). Versions listed more than once are
+// only searched once, but listing the default version more than once (such as in ",") is an error.
+func (s *Site) ParseSearchVersions(versionsStr string) ([]string, error) {
+	switch versionsStr {
+	case "":
+		return nil, errors.New("no search versions")
+	case "*":
+		if len(s.SearchableVersions) == 0 {
+			return nil, errors.New("searching all versions is not supported because no searchable versions are configured")
+		}
+		return s.SearchableVersions, nil
+	}
+	var versions []string
+	seen := map[string]struct{}{}
+	for _, version := range strings.Split(versionsStr, ",") {
+		version = strings.TrimSpace(version)
+		if _, ok := seen[version]; ok {
+			if version == "" {
+				return nil, errors.New("the default search version (empty) is listed more than once")
+			}
+			continue
+		}
+		seen[version] = struct{}{}
+		versions = append(versions, version)
+	}
+	if len(versions) > maxSearchVersions {
+		return nil, fmt.Errorf("too many search versions (%d, maximum %d)", len(versions), maxSearchVersions)
+	}
+	return versions, nil
+}
+
+// searchScoreParams returns the parameters used to rank search results.
+func (s *Site) searchScoreParams() query.ScoreParams {
+	if s.SearchScoreParams != nil {
+		return *s.SearchScoreParams
+	}
+	return query.DefaultScoreParams()
+}
+
+// Complete returns up to n document titles and section headings at the version that complete the
+// prefix (for search-as-you-type).
+func (s *Site) Complete(ctx context.Context, contentVersion string, prefix string, n int) ([]index.Completion, error) {
+	idx, err := s.searchIndex(ctx, contentVersion)
+	if err != nil {
+		return nil, err
+	}
+	return idx.Complete(prefix, n), nil
+}
+
+// SearchBundle returns the search index of the version as a bundle for client-side search (such
+// as in a static export of the site).
+func (s *Site) SearchBundle(ctx context.Context, contentVersion string) (*index.Bundle, error) {
+	idx, err := s.searchIndex(ctx, contentVersion)
+	if err != nil {
+		return nil, err
+	}
+	bundle := idx.Bundle(s.searchScoreParams())
+	bundle.ContentVersion = contentVersion
+	return bundle, nil
+}
+
+// searchIndexCacheEntry is a search index built from a version of the content.
+type searchIndexCacheEntry struct {
+	content http.FileSystem // the content file system that the index was built from
+	stamp   string          // the stamp of the content when the index was built
+	index   *index.Index
+
+	synonyms *query.Synonyms // the search synonyms of the content (see readSearchSynonyms)
+}
+
+// searchIndexBuild is a search index being read or built from a version of the content. Concurrent
+// requests for the index of the version share the build.
+type searchIndexBuild struct {
+	contentVersion string
+	content        http.FileSystem // the content file system that the index is built from
+	stamp          string          // the stamp of the content when the build started
+	cancel         context.CancelFunc
+	waiters        int // the number of callers waiting for the build (guarded by Site.searchIndexesMu)
+
+	done     chan struct{} // closed when the build is finished
+	index    *index.Index
+	synonyms *query.Synonyms
+	err      error
+}
+
+// searchIndex returns the search index for the content version. The index is read from the search
+// index file (if it is up to date) or built once, and reused until the version's file system is
+// replaced (such as when it is refreshed) or its files change (see contentStamp).
+//
+// Concurrent calls for the same version share a single build. If ctx is done, searchIndex returns
+// without waiting for the build, which is canceled when no other calls are waiting for it.
+func (s *Site) searchIndex(ctx context.Context, contentVersion string) (*index.Index, error) {
+	idx, _, err := s.searchIndexAndSynonyms(ctx, contentVersion)
+	return idx, err
+}
+
+// searchIndexAndSynonyms is like searchIndex, but it also returns the content version's search
+// synonyms, which are read along with the index and cached with it.
+func (s *Site) searchIndexAndSynonyms(ctx context.Context, contentVersion string) (*index.Index, *query.Synonyms, error) {
+	content, err := s.Content.OpenVersion(ctx, contentVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	stamp, err := s.contentStamp(contentVersion, content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.searchIndexesMu.Lock()
+	if e := s.searchIndexes[contentVersion]; e != nil && sameFileSystem(e.content, content) && e.stamp == stamp {
+		s.searchIndexesMu.Unlock()
+		return e.index, e.synonyms, nil
+	}
+	b := s.searchIndexBuilds[contentVersion]
+	if b == nil || !sameFileSystem(b.content, content) || b.stamp != stamp {
+		// The build continues if the caller that started it goes away (while others are waiting).
+		buildCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		b = &searchIndexBuild{
+			contentVersion: contentVersion,
+			content:        content,
+			stamp:          stamp,
+			cancel:         cancel,
+			done:           make(chan struct{}),
+		}
+		if s.searchIndexBuilds == nil {
+			s.searchIndexBuilds = map[string]*searchIndexBuild{}
+		}
+		s.searchIndexBuilds[contentVersion] = b
+		go s.runSearchIndexBuild(buildCtx, b)
+	}
+	b.waiters++
+	s.searchIndexesMu.Unlock()
+
+	select {
+	case <-b.done:
+		s.releaseSearchIndexBuild(b)
+		return b.index, b.synonyms, b.err
+	case <-ctx.Done():
+		s.releaseSearchIndexBuild(b)
+		return nil, nil, ctx.Err()
+	}
+}
+
+// runSearchIndexBuild reads or builds the search index (and reads the search synonyms) and caches
+// them (if the build is still the latest one for its version).
+func (s *Site) runSearchIndexBuild(ctx context.Context, b *searchIndexBuild) {
+	// The search index file is only an optimization, so if it is missing, stale, or unreadable, the
+	// index is built instead.
+	idx, err := s.readSearchIndexFile(b.contentVersion, b.stamp)
+	if err != nil {
+		idx, err = s.buildSearchIndex(ctx, b.content, b.contentVersion)
+	}
+	var synonyms *query.Synonyms
+	if err == nil {
+		synonyms, err = readSearchSynonyms(b.content)
+	}
+
+	s.searchIndexesMu.Lock()
+	defer s.searchIndexesMu.Unlock()
+	if s.searchIndexBuilds[b.contentVersion] == b {
+		delete(s.searchIndexBuilds, b.contentVersion)
+		if err == nil {
+			if s.searchIndexes == nil {
+				s.searchIndexes = map[string]*searchIndexCacheEntry{}
+			}
+			s.searchIndexes[b.contentVersion] = &searchIndexCacheEntry{content: b.content, stamp: b.stamp, index: idx, synonyms: synonyms}
+		}
+	}
+	b.index, b.synonyms, b.err = idx, synonyms, err
+	close(b.done)
+}
+
+// releaseSearchIndexBuild is called when a caller stops waiting for the build. When no callers are
+// waiting, the build is canceled (if it is still running), and later calls start a new build.
+func (s *Site) releaseSearchIndexBuild(b *searchIndexBuild) {
+	s.searchIndexesMu.Lock()
+	defer s.searchIndexesMu.Unlock()
+	b.waiters--
+	if b.waiters == 0 {
+		b.cancel()
+		if s.searchIndexBuilds[b.contentVersion] == b {
+			delete(s.searchIndexBuilds, b.contentVersion)
+		}
+	}
+}
+
+// buildSearchIndex builds a search index of all content pages in the file system. The pages are
+// rendered concurrently, and the build stops when ctx is done.
+func (s *Site) buildSearchIndex(ctx context.Context, content http.FileSystem, contentVersion string) (*index.Index, error) {
+	pages, err := s.allContentPages(ctx, content, contentVersion)
 	if err != nil {
 		return nil, err
 	}
-	for _, page := range pages {
+
+	docs := make([]*index.Document, len(pages))
+	err = forEachParallel(ctx, len(pages), func(ctx context.Context, i int) error {
+		page := pages[i]
 		if s.SkipIndexURLPattern != nil && s.SkipIndexURLPattern.MatchString(page.Path) {
 			// this URL matches a pattern that we do not want to index for search, so we will skip it
-			continue
+			return nil
+		}
+		if page.Doc.Meta.SearchExclude {
+			return nil
 		}
 
 		root := markdown.New(markdown.Options{}).Parser().Parse(text.NewReader(page.Data))
 		data, err := s.renderTextContent(ctx, page, root, contentVersion)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		if err := idx.Add(ctx, index.Document{
-			ID:    index.DocID(page.FilePath),
-			Title: markdown.GetTitle(root, page.Data),
-			URL:   s.Base.ResolveReference(&url.URL{Path: page.Path}).String(),
-			Data:  data,
-		}); err != nil {
+		docs[i] = &index.Document{
+			ID:       index.DocID(page.FilePath),
+			Title:    markdown.GetTitle(root, page.Data),
+			URL:      s.Base.ResolveReference(&url.URL{Path: versionedPath(contentVersion, page.Path)}).String(),
+			Path:     page.Path,
+			Data:     data,
+			Tags:     page.Doc.Meta.Tags,
+			Category: page.Doc.Meta.Category,
+			Dir:      topLevelDir(page.FilePath),
+			Keywords: page.Doc.Meta.SearchKeywords,
+			Boost:    page.Doc.Meta.SearchBoost,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Documents are added in order (and not concurrently, which Index.Add does not support).
+	idx, err := index.New()
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
 			return nil, err
 		}
+		if err := idx.Add(ctx, *doc); err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+// versionedPath returns the URL path (relative to the site base) of the path at the content
+// version.
+func versionedPath(contentVersion, path string) string {
+	if contentVersion == "" {
+		return path
 	}
+	return "@" + contentVersion + "/" + path
+}
+
+// topLevelDir returns the top-level directory of the file path (such as "admin" for
+// "admin/config.md"), or empty if the file is not in a directory.
+func topLevelDir(filePath string) string {
+	if i := strings.Index(filePath, "/"); i != -1 {
+		return filePath[:i]
+	}
+	return ""
+}
 
-	return search.Search(query.Parse(queryStr), idx)
+// sameFileSystem reports whether a and b are the same file system value. File systems whose
+// dynamic types are not comparable are assumed to be the same.
+func sameFileSystem(a, b http.FileSystem) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	if t := reflect.TypeOf(a); t != nil && !t.Comparable() {
+		return true
+	}
+	return a == b
 }
 
 func (s *Site) renderTextContent(ctx context.Context, page *ContentPage, node ast.Node, contentVersion string) ([]byte, error) {
@@ -80,14 +419,36 @@ func (s *Site) renderTextContent(ctx context.Context, page *ContentPage, node as
 	return page.Data, err
 }
 
-func (s *Site) renderSearchPage(contentVersion, queryStr string, result *search.Result) ([]byte, error) {
-	query := query.Parse(queryStr)
-	templates, err := s.GetResources("templates", contentVersion)
+// renderSearchPage renders the search page for the result of a search at the content version (or
+// at multiple versions, if contentVersions is non-empty). The params are the URL query parameters of
+// the search, and clickURL (if non-nil) returns the URL that records a click on a result (see
+// searchClickURL).
+func (s *Site) renderSearchPage(contentVersion string, contentVersions []string, queryStr string, params url.Values, result *search.Result, clickURL func(url string, i int) string) ([]byte, error) {
+	templatesVersion := contentVersion
+	if contentVersions != nil {
+		templatesVersion = contentVersions[0]
+	}
+	templates, err := s.GetResources("templates", templatesVersion)
 	if err != nil {
 		return nil, err
 	}
 	tmpl, err := s.getTemplate(templates, searchTemplateName, template.FuncMap{
-		"highlight": func(text string) template.HTML { return highlight(query, text) },
+		"highlight": func(text string, fieldName ...string) (template.HTML, error) {
+			field := query.FieldBody
+			if len(fieldName) > 0 {
+				var ok bool
+				if field, ok = query.FieldByName(fieldName[0]); !ok {
+					return "", fmt.Errorf("highlight: unknown field %q", fieldName[0])
+				}
+			}
+			return highlight(result.Query, text, field), nil
+		},
+		"clickURL": func(url string, i int) string {
+			if clickURL == nil {
+				return url
+			}
+			return clickURL(url, i)
+		},
 	})
 	if err != nil {
 		return nil, err
@@ -95,13 +456,25 @@ func (s *Site) renderSearchPage(contentVersion, queryStr string, result *search.
 
 	data := struct {
 		ContentVersion string
+		Versions       []string // the content versions searched (only when searching multiple versions)
 		Query          string
 		Result         *search.Result
+		PrevPageURL    string // the URL of the previous page of results (empty on the first page)
+		NextPageURL    string // the URL of the next page of results (empty on the last page)
 	}{
 		ContentVersion: contentVersion,
+		Versions:       contentVersions,
 		Query:          queryStr,
 		Result:         result,
 	}
+	if result.Limit > 0 {
+		if result.Offset > 0 {
+			data.PrevPageURL = searchPageURL(params, max(result.Offset-result.Limit, 0), result.Limit)
+		}
+		if result.HasMore {
+			data.NextPageURL = searchPageURL(params, result.Offset+result.Limit, result.Limit)
+		}
+	}
 
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
@@ -110,10 +483,205 @@ func (s *Site) renderSearchPage(contentVersion, queryStr string, result *search.
 	return buf.Bytes(), nil
 }
 
-// highlight returns an HTML fragment with matches of the pattern in text wrapped in <strong>.
-func highlight(query query.Query, text string) template.HTML {
+// searchPageURL returns the relative URL of a page of the search results with the URL query
+// parameters.
+func searchPageURL(params url.Values, offset, limit int) string {
+	pageParams := url.Values{}
+	for name, values := range params {
+		pageParams[name] = values
+	}
+	if offset != 0 {
+		pageParams.Set("offset", strconv.Itoa(offset))
+	} else {
+		pageParams.Del("offset")
+	}
+	pageParams.Set("limit", strconv.Itoa(limit))
+	return "?" + pageParams.Encode()
+}
+
+// searchFilterParams returns the facet filters in the URL query parameters.
+func searchFilterParams(params url.Values) search.Filters {
+	return search.Filters{
+		Categories: params["category"],
+		Tags:       params["tag"],
+		Dirs:       params["dir"],
+	}
+}
+
+// searchResponse is the JSON representation of a page of search results.
+type searchResponse struct {
+	Query          string                 `json:"query"`
+	ContentVersion string                 `json:"contentVersion"`
+	Versions       []string               `json:"versions,omitempty"` // the content versions searched (if multiple)
+	Total          int                    `json:"total"`              // total number of document results
+	Offset         int                    `json:"offset"`             // index of the first document result
+	Limit          int                    `json:"limit"`              // maximum number of document results
+	HasMore        bool                   `json:"hasMore"`            // whether there are more document results after these
+	Results        []searchDocumentResult `json:"results"`
+	Facets         searchFacets           `json:"facets"`
+	Suggestions    []string               `json:"suggestions,omitempty"` // similarly spelled queries (if there are no results)
+}
+
+type searchFacets struct {
+	Categories []searchFacetValue `json:"categories"`
+	Tags       []searchFacetValue `json:"tags"`
+	Dirs       []searchFacetValue `json:"dirs"`
+}
+
+type searchFacetValue struct {
+	Value    string `json:"value"`
+	Count    int    `json:"count"`
+	Selected bool   `json:"selected"`
+}
+
+func newSearchFacetValues(values []search.FacetValue) []searchFacetValue {
+	resp := make([]searchFacetValue, len(values))
+	for i, v := range values {
+		resp[i] = searchFacetValue{Value: v.Value, Count: v.Count, Selected: v.Selected}
+	}
+	return resp
+}
+
+type searchDocumentResult struct {
+	ID       string                `json:"id"`
+	Title    string                `json:"title"`
+	URL      string                `json:"url"`
+	Score    float64               `json:"score"`
+	Versions []string              `json:"versions,omitempty"` // the content versions with matches (if searching multiple)
+	ClickURL string                `json:"clickUrl,omitempty"` // the URL that records a click and redirects to URL (if search analytics is enabled)
+	Pinned   bool                  `json:"pinned,omitempty"`   // whether the document is a best bet for the query
+	Sections []searchSectionResult `json:"sections"`
+}
+
+type searchSectionResult struct {
+	ID         string          `json:"id"`
+	IDStack    []string        `json:"idStack"`
+	Title      string          `json:"title"`
+	TitleStack []string        `json:"titleStack"`
+	URL        string          `json:"url"`                // the document URL with the section's fragment
+	ClickURL   string          `json:"clickUrl,omitempty"` // the URL that records a click and redirects to URL (if search analytics is enabled)
+	Excerpts   []template.HTML `json:"excerpts"`           // HTML excerpts with matches wrapped in <strong>
+
+	// ExcerptInfos describes each excerpt (only if any is from a code block).
+	ExcerptInfos []searchExcerptInfo `json:"excerptInfos,omitempty"`
+}
+
+type searchExcerptInfo struct {
+	Code bool   `json:"code"`           // whether the excerpt is from a code block
+	Lang string `json:"lang,omitempty"` // the language of the code block, if any
+}
+
+// newSearchResponse returns the JSON representation of the search result. If clickURL is non-nil,
+// it returns the URL that records a click on a result (see searchClickURL).
+func newSearchResponse(contentVersion string, contentVersions []string, queryStr string, result *search.Result, clickURL func(url string, i int) string) *searchResponse {
+	resp := &searchResponse{
+		Query:          queryStr,
+		ContentVersion: contentVersion,
+		Versions:       contentVersions,
+		Total:          result.Total,
+		Offset:         result.Offset,
+		Limit:          result.Limit,
+		HasMore:        result.HasMore,
+		Results:        []searchDocumentResult{},
+		Facets: searchFacets{
+			Categories: newSearchFacetValues(result.Facets.Categories),
+			Tags:       newSearchFacetValues(result.Facets.Tags),
+			Dirs:       newSearchFacetValues(result.Facets.Dirs),
+		},
+		Suggestions: result.Suggestions,
+	}
+	clickURLOrEmpty := func(url string, i int) string {
+		if clickURL == nil {
+			return ""
+		}
+		return clickURL(url, i)
+	}
+	for i, dr := range result.DocumentResults {
+		sections := make([]searchSectionResult, len(dr.SectionResults))
+		for j, sr := range dr.SectionResults {
+			u := dr.URL
+			if sr.ID != "" {
+				u += "#" + sr.ID
+			}
+			excerpts := make([]template.HTML, len(sr.Excerpts))
+			for k, excerpt := range sr.Excerpts {
+				field := query.FieldBody
+				if k < len(sr.ExcerptInfos) && sr.ExcerptInfos[k].Code {
+					field = query.FieldCode
+				}
+				excerpts[k] = highlight(result.Query, excerpt, field)
+			}
+			var excerptInfos []searchExcerptInfo
+			for _, info := range sr.ExcerptInfos {
+				if info.Code {
+					excerptInfos = make([]searchExcerptInfo, len(sr.ExcerptInfos))
+					for k, info := range sr.ExcerptInfos {
+						excerptInfos[k] = searchExcerptInfo{Code: info.Code, Lang: info.Lang}
+					}
+					break
+				}
+			}
+			sections[j] = searchSectionResult{
+				ID:         sr.ID,
+				IDStack:    sr.IDStack,
+				Title:      sr.Title,
+				TitleStack: sr.TitleStack,
+				URL:        u,
+				ClickURL:   clickURLOrEmpty(u, i),
+				Excerpts:   excerpts,
+
+				ExcerptInfos: excerptInfos,
+			}
+		}
+		resp.Results = append(resp.Results, searchDocumentResult{
+			ID:       string(dr.ID),
+			Title:    dr.Title,
+			URL:      dr.URL,
+			Score:    dr.Score,
+			Versions: dr.Versions,
+			ClickURL: clickURLOrEmpty(dr.URL, i),
+			Pinned:   dr.Pinned,
+			Sections: sections,
+		})
+	}
+	return resp
+}
+
+// completeResponse is the JSON representation of completions of a prefix.
+type completeResponse struct {
+	Query          string               `json:"query"`
+	ContentVersion string               `json:"contentVersion"`
+	Completions    []completionResponse `json:"completions"`
+}
+
+type completionResponse struct {
+	Title         string `json:"title"`         // the document title or section heading
+	DocumentTitle string `json:"documentTitle"` // the title of the document containing the section
+	URL           string `json:"url"`           // the document URL (with the section's fragment)
+}
+
+func newCompleteResponse(contentVersion, prefix string, completions []index.Completion) *completeResponse {
+	resp := &completeResponse{
+		Query:          prefix,
+		ContentVersion: contentVersion,
+		Completions:    make([]completionResponse, len(completions)),
+	}
+	for i, c := range completions {
+		resp.Completions[i] = completionResponse{
+			Title:         c.Title,
+			DocumentTitle: c.DocumentTitle,
+			URL:           c.URL,
+		}
+	}
+	return resp
+}
+
+// highlight returns an HTML fragment with matches of the query in text (which is in the field)
+// wrapped in <strong>. Terms that the query excludes, or qualifies with other fields, are not
+// highlighted.
+func highlight(query query.Query, text string, field query.Field) template.HTML {
 	// Highlight longer matches first (among matches starting at the same position).
-	matches := query.FindAllIndex(text)
+	matches := query.FindAllIndex(text, field)
 	sort.Slice(matches, func(i, j int) bool {
 		return (matches[i][0] < matches[j][0]) || (matches[i][0] == matches[j][0] && matches[i][1] > matches[j][1])
 	})
@@ -0,0 +1,105 @@
+package docsite
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sourcegraph/docsite/internal/search"
+	"github.com/sourcegraph/docsite/internal/search/analytics"
+)
+
+// logSearchEvent records the event with the site's search analytics sink (if any).
+func (s *Site) logSearchEvent(e analytics.Event) {
+	if s.SearchAnalytics == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if err := s.SearchAnalytics.Log(e); err != nil {
+		log.Printf("# Error logging search analytics event: %s", err)
+	}
+}
+
+// logSearch records a search for the query at the content version (or at multiple versions, if
+// contentVersions is non-empty) that took the given time.
+func (s *Site) logSearch(contentVersion string, contentVersions []string, queryStr string, result *search.Result, latency time.Duration) {
+	if strings.TrimSpace(queryStr) == "" {
+		return
+	}
+	s.logSearchEvent(analytics.Event{
+		Type:           analytics.EventSearch,
+		Query:          queryStr,
+		ContentVersion: contentVersion,
+		Versions:       contentVersions,
+		Offset:         result.Offset,
+		Results:        result.Total,
+		LatencyMS:      float64(latency) / float64(time.Millisecond),
+	})
+}
+
+// searchClickURL returns a function that returns the URL (under basePath) that records a click on
+// the i'th document result of the search with the URL query parameters and redirects to url (the
+// URL of the document or one of its sections). If the site has no search analytics sink, it returns
+// nil.
+func (s *Site) searchClickURL(basePath string, params url.Values, result *search.Result) func(url string, i int) string {
+	if s.SearchAnalytics == nil {
+		return nil
+	}
+	return func(u string, i int) string {
+		clickParams := url.Values{}
+		for _, name := range []string{"q", "v", "versions"} {
+			if value := params.Get(name); value != "" {
+				clickParams.Set(name, value)
+			}
+		}
+		clickParams.Set("url", u)
+		clickParams.Set("rank", strconv.Itoa(result.Offset+i+1))
+		return path.Join(basePath, "search", "click") + "?" + clickParams.Encode()
+	}
+}
+
+// serveSearchClick records a click on a search result (see searchClickURL) and redirects to the
+// result's URL, which must be a URL path under basePath.
+func (s *Site) serveSearchClick(w http.ResponseWriter, r *http.Request, basePath string) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	params := r.URL.Query()
+	u, err := url.Parse(params.Get("url"))
+	if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasPrefix(u.Path, basePath) || strings.Contains(u.Path, `\`) {
+		// Only redirect to pages on the site, so that this isn't an open redirect.
+		http.Error(w, "invalid url parameter (must be a URL path on the site)", http.StatusBadRequest)
+		return
+	}
+	rank, err := intParam(params, "rank", 0, 0, math.MaxInt32)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == "GET" {
+		var contentVersions []string
+		if versionsStr := params.Get("versions"); versionsStr != "" {
+			contentVersions = strings.Split(versionsStr, ",")
+		}
+		s.logSearchEvent(analytics.Event{
+			Type:           analytics.EventClick,
+			Query:          params.Get("q"),
+			ContentVersion: params.Get("v"),
+			Versions:       contentVersions,
+			URL:            u.String(),
+			Rank:           rank,
+		})
+	}
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
@@ -0,0 +1,126 @@
+package docsite
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"golang.org/x/tools/godoc/vfs/httpfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
+
+	"github.com/sourcegraph/docsite/internal/search/analytics"
+)
+
+type recordingSink struct {
+	events []analytics.Event
+}
+
+func (s *recordingSink) Log(e analytics.Event) error {
+	s.events = append(s.events, e)
+	return nil
+}
+
+func TestSite_Handler_searchAnalytics(t *testing.T) {
+	sink := &recordingSink{}
+	site := Site{
+		Content: versionedFileSystem{
+			"": httpfs.New(mapfs.New(map[string]string{
+				"a.md":                               "# A\n\nConfigure SSO.",
+				"_resources/templates/root.html":     `{{block "content" .}}empty{{end}}`,
+				"_resources/templates/document.html": `{{define "content"}}{{markdown .Content}}{{end}}`,
+				"_resources/templates/search.html":   `{{define "content"}}{{range $i, $dr := .Result.DocumentResults}}<a href="{{clickURL $dr.URL $i}}">{{$dr.Title}}</a>{{end}}{{end}}`,
+			})),
+		},
+		Base:            &url.URL{Path: "/"},
+		SearchAnalytics: sink,
+	}
+	handler := site.Handler()
+
+	get := func(t *testing.T, urlStr string) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", urlStr, nil)
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Search.
+	rr := get(t, "/search.json?q=SSO&log=1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got HTTP status %d, want %d", rr.Code, http.StatusOK)
+	}
+	if len(sink.events) != 1 {
+		t.Fatalf("got %d events, want 1", len(sink.events))
+	}
+	if e := sink.events[0]; e.Type != analytics.EventSearch || e.Query != "SSO" || e.Results != 1 || e.Time.IsZero() {
+		t.Errorf("got event %+v, want search for SSO with 1 result", e)
+	}
+	var resp struct {
+		Results []struct {
+			ClickURL string
+			Sections []struct{ ClickURL string }
+		}
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 1 || len(resp.Results[0].Sections) != 1 {
+		t.Fatalf("got results %+v, want 1 result with 1 section", resp.Results)
+	}
+	clickURL := resp.Results[0].ClickURL
+	if want := "/search/click?q=SSO&rank=1&url=%2Fa"; clickURL != want {
+		t.Errorf("got click URL %q, want %q", clickURL, want)
+	}
+
+	// Click.
+	rr = get(t, clickURL)
+	if rr.Code != http.StatusFound {
+		t.Fatalf("got HTTP status %d, want %d", rr.Code, http.StatusFound)
+	}
+	if got, want := rr.Header().Get("Location"), "/a"; got != want {
+		t.Errorf("got redirect Location %q, want %q", got, want)
+	}
+	if len(sink.events) != 2 {
+		t.Fatalf("got %d events, want 2", len(sink.events))
+	}
+	if e := sink.events[1]; e.Type != analytics.EventClick || e.Query != "SSO" || e.URL != "/a" || e.Rank != 1 {
+		t.Errorf("got event %+v, want click on /a for SSO", e)
+	}
+
+	t.Run("search page", func(t *testing.T) {
+		n := len(sink.events)
+		rr := get(t, "/search?q=SSO")
+		if len(sink.events) != n+1 {
+			t.Errorf("got %d events, want 1 for search page", len(sink.events)-n)
+		}
+		if want := `<a href="/search/click?q=SSO&amp;rank=1&amp;url=%2Fa">A</a>`; rr.Body.String() != want {
+			t.Errorf("got body %q, want %q", rr.Body.String(), want)
+		}
+	})
+
+	t.Run("instant search", func(t *testing.T) {
+		n := len(sink.events)
+		get(t, "/search.json?q=SS")
+		if len(sink.events) != n {
+			t.Errorf("got event %+v, want none for JSON search without log=1", sink.events[n:])
+		}
+	})
+
+	t.Run("empty query", func(t *testing.T) {
+		n := len(sink.events)
+		get(t, "/search.json?q=&log=1")
+		if len(sink.events) != n {
+			t.Errorf("got event %+v, want none for empty query", sink.events[n:])
+		}
+	})
+
+	t.Run("click on other site", func(t *testing.T) {
+		for _, u := range []string{"https://example.com/a", "//example.com/a", `/\example.com`} {
+			if rr := get(t, "/search/click?q=SSO&url="+url.QueryEscape(u)); rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: got HTTP status %d, want %d", u, rr.Code, http.StatusBadRequest)
+			}
+		}
+	})
+}
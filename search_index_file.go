@@ -0,0 +1,103 @@
+package docsite
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/docsite/internal/search/index"
+)
+
+// searchIndexFileHeader precedes the encoded index in a search index file.
+type searchIndexFileHeader struct {
+	ContentVersion string
+	Stamp          string // the stamp of the content when the index was built
+}
+
+// errSearchIndexStale is returned when a search index file was built from different content.
+var errSearchIndexStale = errors.New("search index file is stale (content changed since it was built)")
+
+// SearchIndexFile returns the path of the search index file for the content version, or empty if
+// there is none. See Site.SearchIndexPath.
+func (s *Site) SearchIndexFile(contentVersion string) string {
+	if !strings.Contains(s.SearchIndexPath, "$VERSION") {
+		if contentVersion != "" {
+			return ""
+		}
+		return s.SearchIndexPath
+	}
+	return strings.ReplaceAll(s.SearchIndexPath, "$VERSION", contentVersion)
+}
+
+// WriteSearchIndex builds the search index for the content version and writes it to w, in the
+// format of the files read from SearchIndexPath.
+func (s *Site) WriteSearchIndex(ctx context.Context, contentVersion string, w io.Writer) error {
+	content, err := s.Content.OpenVersion(ctx, contentVersion)
+	if err != nil {
+		return err
+	}
+	stamp, err := fileSystemStamp(content)
+	if err != nil {
+		return err
+	}
+	idx, err := s.buildSearchIndex(ctx, content, contentVersion)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(w)
+	if err := gob.NewEncoder(zw).Encode(searchIndexFileHeader{ContentVersion: contentVersion, Stamp: stamp}); err != nil {
+		return err
+	}
+	if err := idx.Encode(zw); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// LoadSearchIndex loads the search index for the content version, so that the first search
+// doesn't need to wait for it. The index is read from the search index file if it is up to date,
+// and otherwise it is built.
+func (s *Site) LoadSearchIndex(ctx context.Context, contentVersion string) error {
+	_, err := s.searchIndex(ctx, contentVersion)
+	return err
+}
+
+// readSearchIndexFile reads the search index file for the content version. It returns an error if
+// there is no file, or if the file was built from content with a different stamp or by a version of
+// docsite with a different index format (index.ErrFormatVersion).
+func (s *Site) readSearchIndexFile(contentVersion, stamp string) (*index.Index, error) {
+	path := s.SearchIndexFile(contentVersion)
+	if path == "" {
+		return nil, &os.PathError{Op: "open", Path: "search index file", Err: os.ErrNotExist}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "read search index file %s", path)
+	}
+	r := bufio.NewReader(zr) // shared by both gob decoders so that neither reads ahead of the other
+
+	var header searchIndexFileHeader
+	if err := gob.NewDecoder(r).Decode(&header); err != nil {
+		return nil, errors.WithMessagef(err, "read search index file %s", path)
+	}
+	if header.ContentVersion != contentVersion || header.Stamp != stamp {
+		return nil, errors.WithMessage(errSearchIndexStale, path)
+	}
+	idx, err := index.Decode(r)
+	if err != nil {
+		return nil, errors.WithMessage(err, path)
+	}
+	return idx, nil
+}
@@ -0,0 +1,88 @@
+package docsite
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/godoc/vfs/httpfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
+
+	"github.com/sourcegraph/docsite/internal/search"
+)
+
+func TestSite_searchIndexFile(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	files := map[string]string{"a.md": "# A\n\nfoo", "b/c.md": "# C\n\nfoo bar"}
+	site := Site{
+		Content: versionedFileSystem{
+			"":      httpfs.New(mapfs.New(files)),
+			"other": httpfs.New(mapfs.New(map[string]string{"d.md": "foo"})),
+		},
+		Base:            &url.URL{Path: "/"},
+		SearchIndexPath: filepath.Join(dir, "index-$VERSION.gz"),
+	}
+
+	if got, want := site.SearchIndexFile("other"), filepath.Join(dir, "index-other.gz"); got != want {
+		t.Errorf("got file %q, want %q", got, want)
+	}
+	if got := (&Site{SearchIndexPath: "index.gz"}).SearchIndexFile("other"); got != "" {
+		t.Errorf("got file %q for non-default version without $VERSION, want none", got)
+	}
+
+	writeSearchIndex := func(contentVersion string) {
+		t.Helper()
+		f, err := os.Create(site.SearchIndexFile(contentVersion))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := site.WriteSearchIndex(ctx, contentVersion, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeSearchIndex("")
+	writeSearchIndex("other")
+
+	stamp, err := fileSystemStamp(site.Content.(versionedFileSystem)[""])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := site.readSearchIndexFile("", "x"); errors.Cause(err) != errSearchIndexStale {
+		t.Errorf("got error %v for different stamp, want errSearchIndexStale", err)
+	}
+	if _, err := site.readSearchIndexFile("other", stamp); errors.Cause(err) != errSearchIndexStale {
+		t.Errorf("got error %v for different version, want errSearchIndexStale", err)
+	}
+
+	search := func(contentVersion, queryStr string) []string {
+		t.Helper()
+		result, err := site.Search(ctx, contentVersion, queryStr, search.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return toResultsList(result)
+	}
+
+	// Change the content without changing its stamp (the file sizes and modification times), to
+	// check that the index is read from the file.
+	files["a.md"] = "# A\n\nfoe"
+	if got, want := search("", "foo"), []string{"a.md#: foo", "b/c.md#: foo bar"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got results %q, want %q", got, want)
+	}
+	if err := site.LoadSearchIndex(ctx, "other"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A stale file is ignored.
+	files["a.md"] = "# A\n\nqux!"
+	site.Content.(versionedFileSystem)[""] = httpfs.New(mapfs.New(files))
+	if got, want := search("", "qux"), []string{"a.md#: qux!"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got results %q, want %q", got, want)
+	}
+}
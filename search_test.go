@@ -3,12 +3,22 @@ package docsite
 import (
 	"context"
 	"fmt"
+	"html/template"
+	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/pkg/errors"
 
 	"github.com/sourcegraph/docsite/internal/search"
+	"github.com/sourcegraph/docsite/internal/search/index"
 	"github.com/sourcegraph/docsite/internal/search/query"
 	"github.com/sourcegraph/docsite/markdown"
 	"golang.org/x/tools/godoc/vfs/httpfs"
@@ -46,13 +56,25 @@ func TestSearch(t *testing.T) {
 				"b": {"b.md", "a.md#: b"},
 			},
 		},
+		"plain text": {
+			pages: map[string]string{
+				"a.md": "---\ncategory: zzz\n---\nSee [the docs](../foo.md) and <span class=\"secret\">more</span>.",
+			},
+			wantQueryResults: map[string][]string{
+				"docs":     {"a.md#: See the docs and more."},
+				"foo":      nil,
+				"secret":   nil,
+				"category": nil,
+			},
+		},
 		"evaluate markdown funcs": {
 			pages: map[string]string{
 				"a.md": "<div markdown-func=test-func />",
 				"b.md": "",
 			},
 			wantQueryResults: map[string][]string{
-				"x": {"a.md#: xyz"},
+				"xyz": {"a.md#: xyz"},
+				"x":   nil,
 			},
 		},
 	}
@@ -67,7 +89,7 @@ func TestSearch(t *testing.T) {
 			}
 			for query, wantResults := range test.wantQueryResults {
 				t.Run(query, func(t *testing.T) {
-					result, err := site.Search(ctx, "", query)
+					result, err := site.Search(ctx, "", query, search.Options{})
 					if err != nil {
 						t.Fatal(err)
 					}
@@ -81,6 +103,480 @@ func TestSearch(t *testing.T) {
 	}
 }
 
+func TestSearch_metadata(t *testing.T) {
+	site := Site{
+		Content: versionedFileSystem{
+			"": httpfs.New(mapfs.New(map[string]string{
+				"a.md":       "---\ntags: [foo]\ncategory: bar\n---\n\na",
+				"b.md":       "foo bar",
+				"c.md":       "---\nsearchKeywords: [deploy, set up]\n---\n\nc",
+				"d.md":       "---\nsearchExclude: true\n---\n\ndeploy",
+				"e.md":       "---\nsearchBoost: 10\n---\n\nbaz",
+				"f.md":       "baz baz baz",
+				"g/index.md": "g",
+			})),
+		},
+		Base:           &url.URL{Path: "/"},
+		SearchBestBets: map[string]string{"Install  Guide": "/b", "g": "/g", "missing": "/x"},
+	}
+	tests := map[string][]index.DocID{
+		"tag:foo":       {"a.md"},
+		"category:bar":  {"a.md"},
+		"foo -tag:foo":  {"b.md"},
+		"deploy":        {"c.md"},
+		`"set up"`:      {"c.md"},
+		"baz":           {"e.md", "f.md"},
+		"install guide": {"b.md"},
+		"g":             {"g/index.md"},
+		"missing":       nil,
+	}
+	for queryStr, want := range tests {
+		t.Run(queryStr, func(t *testing.T) {
+			result, err := site.Search(context.Background(), "", queryStr, search.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []index.DocID
+			for _, dr := range result.DocumentResults {
+				got = append(got, dr.ID)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSearch_path(t *testing.T) {
+	site := Site{
+		Content: versionedFileSystem{
+			"5.2": httpfs.New(mapfs.New(map[string]string{
+				"admin/install.md": "install",
+				"upgrade.md":       "Upgrade to 5.2",
+				"other.md":         "other",
+			})),
+		},
+		Base: &url.URL{Path: "/help/"},
+	}
+	// The site base and the version are in every page's URL, so they must not match the path.
+	tests := map[string][]index.DocID{
+		"help":         nil,
+		"path:help":    nil,
+		"5.2":          {"upgrade.md"},
+		"2":            {"upgrade.md"},
+		"path:admin":   {"admin/install.md"},
+		"path:upgrade": {"upgrade.md"},
+	}
+	for queryStr, want := range tests {
+		t.Run(queryStr, func(t *testing.T) {
+			result, err := site.Search(context.Background(), "5.2", queryStr, search.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []index.DocID
+			for _, dr := range result.DocumentResults {
+				got = append(got, dr.ID)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSearch_pagination(t *testing.T) {
+	site := Site{
+		Content: versionedFileSystem{
+			"": httpfs.New(mapfs.New(map[string]string{
+				"a.md": "x x x x",
+				"b.md": "x x x",
+				"c.md": "x x",
+				"d.md": "x",
+			})),
+		},
+		Base: &url.URL{Path: "/"},
+	}
+	tests := []struct {
+		offset, limit int
+		ids           []index.DocID
+		hasMore       bool
+	}{
+		{ids: []index.DocID{"a.md", "b.md", "c.md", "d.md"}},
+		{limit: 2, ids: []index.DocID{"a.md", "b.md"}, hasMore: true},
+		{offset: 2, limit: 2, ids: []index.DocID{"c.md", "d.md"}},
+		{offset: 3, limit: 2, ids: []index.DocID{"d.md"}},
+		{offset: 9, limit: 2},
+	}
+	for _, want := range tests {
+		t.Run(fmt.Sprintf("offset %d limit %d", want.offset, want.limit), func(t *testing.T) {
+			result, err := site.Search(context.Background(), "", "x", search.Options{Offset: want.offset, Limit: want.limit})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []index.DocID
+			for _, dr := range result.DocumentResults {
+				ids = append(ids, dr.ID)
+				if len(dr.SectionResults) == 0 {
+					t.Errorf("%s: got no section results", dr.ID)
+				}
+			}
+			if !reflect.DeepEqual(ids, want.ids) {
+				t.Errorf("got %v, want %v", ids, want.ids)
+			}
+			if result.HasMore != want.hasMore {
+				t.Errorf("got HasMore %v, want %v", result.HasMore, want.hasMore)
+			}
+			if result.Total != 4 {
+				t.Errorf("got Total %d, want 4", result.Total)
+			}
+		})
+	}
+}
+
+func TestSearchPageURL(t *testing.T) {
+	if got, want := searchPageURL(url.Values{"q": {"a b"}, "offset": {"10"}}, 0, 10), "?limit=10&q=a+b"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := searchPageURL(url.Values{"q": {"a"}, "v": {"v1"}, "tag": {"x", "y"}}, 20, 10), "?limit=10&offset=20&q=a&tag=x&tag=y&v=v1"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSearch_suggestions(t *testing.T) {
+	site := Site{
+		Content: versionedFileSystem{
+			"": httpfs.New(mapfs.New(map[string]string{
+				"a.md": "# Authentication\n\nConfigure the authentication provider.",
+				"b.md": "Provide a token.",
+			})),
+		},
+		Base: &url.URL{Path: "/"},
+	}
+	tests := map[string][]string{
+		"authentication":          nil,
+		"authentcation":           {"authentication"},
+		"configre authentcation":  {"configure authentication"},
+		"tokn":                    {"token"},
+		"authentcation -provider": nil,
+		"zzzzzz":                  nil,
+	}
+	for queryStr, want := range tests {
+		t.Run(queryStr, func(t *testing.T) {
+			result, err := site.Search(context.Background(), "", queryStr, search.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result.Suggestions, want) {
+				t.Errorf("got suggestions %q, want %q", result.Suggestions, want)
+			}
+		})
+	}
+}
+
+func TestSearch_synonyms(t *testing.T) {
+	ctx := context.Background()
+	otherFiles := map[string]string{
+		"a.md": "Configure single sign-on.",
+	}
+	content := versionedFileSystem{
+		"": httpfs.New(mapfs.New(map[string]string{
+			"a.md":                           "Configure single sign-on.",
+			"b.md":                           "Configure SSO.",
+			"_resources/search/synonyms.txt": "# Authentication\nsso, single sign-on\n",
+		})),
+		"other": httpfs.New(mapfs.New(otherFiles)),
+	}
+	site := Site{Content: content, Base: &url.URL{Path: "/"}}
+
+	result, err := site.Search(ctx, "", "sso", search.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := toResultsList(result), []string{"b.md#: Configure SSO.", "a.md#: Configure single sign-on."}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got results %q, want %q (literal match first)", got, want)
+	}
+	if got, want := highlight(result.Query, "Configure single sign-on.", query.FieldBody), template.HTML("Configure <strong>single sign-on</strong>."); got != want {
+		t.Errorf("got highlight %q, want %q", got, want)
+	}
+
+	// The synonyms are per version.
+	result, err = site.Search(ctx, "other", "sso", search.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 0 {
+		t.Errorf("got %d results for version without synonyms, want 0", result.Total)
+	}
+
+	t.Run("multiple versions", func(t *testing.T) {
+		result, err := site.SearchVersions(ctx, []string{"", "other"}, "sso", search.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Total != 2 {
+			t.Errorf("got %d results, want 2 (with the synonyms of any version)", result.Total)
+		}
+	})
+
+	t.Run("cached with the index", func(t *testing.T) {
+		total := func() int {
+			t.Helper()
+			result, err := site.Search(ctx, "other", "sso", search.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return result.Total
+		}
+
+		// The file system is not replaced, so the cached synonyms are reused.
+		otherFiles["_resources/search/synonyms.txt"] = "sso, single sign-on\n"
+		if got := total(); got != 0 {
+			t.Errorf("got %d results, want 0 (with the cached synonyms)", got)
+		}
+		content["other"] = httpfs.New(mapfs.New(otherFiles))
+		if got := total(); got != 1 {
+			t.Errorf("got %d results, want 1 (with the new synonyms)", got)
+		}
+	})
+}
+
+func TestSite_ParseSearchVersions(t *testing.T) {
+	site := Site{SearchableVersions: []string{"", "3.0"}}
+	tests := map[string]struct {
+		want    []string
+		wantErr bool
+	}{
+		"*":                       {want: []string{"", "3.0"}},
+		"a,b":                     {want: []string{"a", "b"}},
+		",a":                      {want: []string{"", "a"}},
+		"a, b ,a":                 {want: []string{"a", "b"}},
+		"":                        {wantErr: true},
+		",":                       {wantErr: true},
+		"a,,b,":                   {wantErr: true},
+		"1,2,3,4,5,6,7,8,9,10":    {want: []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}},
+		"1,2,3,4,5,6,7,8,9,10,11": {wantErr: true},
+	}
+	for versionsStr, test := range tests {
+		t.Run(versionsStr, func(t *testing.T) {
+			versions, err := site.ParseSearchVersions(versionsStr)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(versions, test.want) {
+				t.Errorf("got versions %q, want %q", versions, test.want)
+			}
+		})
+	}
+}
+
+func TestSite_searchIndex(t *testing.T) {
+	ctx := context.Background()
+	files := map[string]string{"a.md": "a"}
+	site := Site{
+		Content: versionedFileSystem{
+			"":      httpfs.New(mapfs.New(files)),
+			"other": httpfs.New(mapfs.New(map[string]string{"b.md": "b"})),
+		},
+		Base: &url.URL{Path: "/"},
+	}
+	searchIndex := func(version string) *index.Index {
+		t.Helper()
+		idx, err := site.searchIndex(ctx, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return idx
+	}
+
+	idx := searchIndex("")
+	if searchIndex("") != idx {
+		t.Error("got new index for unchanged content, want cached index")
+	}
+	if searchIndex("other") == idx {
+		t.Error("got same index for different versions")
+	}
+
+	files["a.md"] = "aa"
+	if searchIndex("") != idx {
+		t.Error("got new index after content changed in the same file system, want cached index")
+	}
+
+	site.Content.(versionedFileSystem)[""] = httpfs.New(mapfs.New(files))
+	idx2 := searchIndex("")
+	if idx2 == idx {
+		t.Error("got cached index after file system was replaced, want new index")
+	}
+
+	t.Run("local directory", func(t *testing.T) {
+		defer func(d time.Duration) { localStampInterval = d }(localStampInterval)
+		localStampInterval = time.Hour
+
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "a.md"), []byte("a"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		site.Content.(versionedFileSystem)["local"] = http.Dir(dir)
+		idx := searchIndex("local")
+		if err := os.WriteFile(filepath.Join(dir, "a.md"), []byte("aa"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if searchIndex("local") != idx {
+			t.Error("got new index before the stamp interval elapsed, want cached index")
+		}
+		localStampInterval = 0
+		if searchIndex("local") == idx {
+			t.Error("got cached index after content changed, want new index")
+		}
+	})
+}
+
+func TestSite_searchIndex_concurrent(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	testMarkdownFuncs = markdown.FuncMap{
+		"test-func": func(context.Context, markdown.FuncInfo, map[string]string) (string, error) {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-release
+			return "xyz", nil
+		},
+	}
+	t.Cleanup(func() { testMarkdownFuncs = nil })
+
+	site := Site{
+		Content: versionedFileSystem{
+			"": httpfs.New(mapfs.New(map[string]string{"a.md": "<div markdown-func=test-func />", "b.md": "b"})),
+		},
+		Base: &url.URL{Path: "/"},
+	}
+	waiters := func() int {
+		site.searchIndexesMu.Lock()
+		defer site.searchIndexesMu.Unlock()
+		if b := site.searchIndexBuilds[""]; b != nil {
+			return b.waiters
+		}
+		return 0
+	}
+
+	const n = 5
+	var wg sync.WaitGroup
+	indexes := make([]*index.Index, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			idx, err := site.searchIndex(context.Background(), "")
+			if err != nil {
+				t.Error(err)
+			}
+			indexes[i] = idx
+		}(i)
+		if i == 0 {
+			<-started
+		}
+	}
+	for waiters() < n {
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+
+	for i, idx := range indexes {
+		if idx == nil || idx != indexes[0] {
+			t.Errorf("got index %d %p, want the shared index %p", i, idx, indexes[0])
+		}
+	}
+}
+
+func TestSite_searchIndex_canceled(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	testMarkdownFuncs = markdown.FuncMap{
+		"test-func": func(context.Context, markdown.FuncInfo, map[string]string) (string, error) {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-release
+			return "xyz", nil
+		},
+	}
+	t.Cleanup(func() { testMarkdownFuncs = nil })
+
+	site := Site{
+		Content: versionedFileSystem{
+			"": httpfs.New(mapfs.New(map[string]string{"a.md": "<div markdown-func=test-func />"})),
+		},
+		Base: &url.URL{Path: "/"},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		_, err := site.searchIndex(ctx, "")
+		errc <- err
+	}()
+	<-started
+	site.searchIndexesMu.Lock()
+	b := site.searchIndexBuilds[""]
+	site.searchIndexesMu.Unlock()
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+
+	// The abandoned build stops, and its result is not cached.
+	close(release)
+	<-b.done
+	if !errors.Is(b.err, context.Canceled) {
+		t.Errorf("got build error %v, want %v", b.err, context.Canceled)
+	}
+
+	result, err := site.Search(context.Background(), "", "xyz", search.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := toResultsList(result), []string{"a.md#: xyz"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got results %v, want %v", got, want)
+	}
+}
+
+func TestSite_SearchBundle(t *testing.T) {
+	testMarkdownFuncs = markdown.FuncMap{
+		"test-func": func(context.Context, markdown.FuncInfo, map[string]string) (string, error) {
+			return "xyz", nil
+		},
+	}
+	t.Cleanup(func() { testMarkdownFuncs = nil })
+
+	site := Site{
+		Content: versionedFileSystem{
+			"other": httpfs.New(mapfs.New(map[string]string{
+				"a.md": "# A\n\n<div markdown-func=test-func />",
+			})),
+		},
+		Base: &url.URL{Path: "/"},
+	}
+	bundle, err := site.SearchBundle(context.Background(), "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.ContentVersion != "other" {
+		t.Errorf("got content version %q, want %q", bundle.ContentVersion, "other")
+	}
+	if len(bundle.Documents) != 1 || bundle.Documents[0].URL != "/@other/a" {
+		t.Fatalf("got documents %+v, want /@other/a", bundle.Documents)
+	}
+	if got, want := bundle.Documents[0].Sections[0].Text, "xyz"; got != want {
+		t.Errorf("got text %q, want %q (evaluated markdown func)", got, want)
+	}
+	if _, ok := bundle.Terms["xyz"]; !ok {
+		t.Error("got no term for markdown func output")
+	}
+}
+
 func toResultsList(result *search.Result) []string {
 	var l []string
 	for _, dr := range result.DocumentResults {
@@ -119,13 +615,28 @@ func TestHighlight(t *testing.T) {
 		{
 			query: "ab bc",
 			text:  "abc",
-			want:  "<strong>ab</strong><strong>c</strong>",
+			want:  "abc",
 		},
 		{
 			query: "a b c ab abc bc",
 			text:  "abc",
 			want:  "<strong>abc</strong>",
 		},
+		{
+			query: "a -b",
+			text:  "a b",
+			want:  "<strong>a</strong> b",
+		},
+		{
+			query: "aa title:bb code:cc",
+			text:  "aa bb cc",
+			want:  "<strong>aa</strong> bb cc",
+		},
+		{
+			query: `"a b" c`,
+			text:  "a b c a",
+			want:  "<strong>a b</strong> <strong>c</strong> a",
+		},
 		{
 			query: "aaaAaaa",
 			text:  "\x9f\x92\xa1 aaaaaaA",
@@ -134,8 +645,7 @@ func TestHighlight(t *testing.T) {
 	}
 	for i, test := range tests {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			query := query.Parse(test.query)
-			got := highlight(query, test.text)
+			got := highlight(query.Parse(test.query), test.text, query.FieldBody)
 			if string(got) != test.want {
 				t.Errorf("got %q, want %q", got, test.want)
 			}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"html/template"
+	"log"
 	"net/http"
 	"net/url"
 	"os"
@@ -12,9 +13,12 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 
+	"github.com/sourcegraph/docsite/internal/search/analytics"
+	"github.com/sourcegraph/docsite/internal/search/query"
 	"github.com/sourcegraph/docsite/markdown"
 )
 
@@ -63,6 +67,59 @@ type Site struct {
 	// SkipIndexURLPattern is a regexp matching URLs to ignore when searching. Any files that have a URL that match this
 	// pattern will be ignored from the search index.
 	SkipIndexURLPattern *regexp.Regexp
+
+	// SearchScoreParams are the parameters used to rank search results. If nil,
+	// query.DefaultScoreParams is used.
+	SearchScoreParams *query.ScoreParams
+
+	// RobotsRules are the rules for crawlers in the site's robots.txt file (see RobotsTxt).
+	RobotsRules []RobotsRule
+
+	// IndexedVersions are the content versions whose pages search engines may index (with "" for the
+	// default version). The pages of other versions are rendered with a robots meta tag of
+	// "noindex,nofollow" (see PageData.RobotsMeta), and have no sitemaps (see Sitemap).
+	IndexedVersions []string
+
+	// SitemapVersions are the content versions (other than the default version) whose sitemaps are
+	// listed in the sitemap index, if they are IndexedVersions (see SitemapIndex).
+	SitemapVersions []string
+
+	// Feeds maps content directories (such as "releases") to the URL paths (relative to Base and
+	// without a file extension, such as "releases/feed") of the Atom and RSS feeds of the pages in
+	// the directory (see Feed).
+	Feeds map[string]string
+
+	// SearchableVersions are the content versions that are searched when searching all versions
+	// ("*"). The VersionedFileSystem can't list its versions, so they must be listed here.
+	SearchableVersions []string
+
+	// SearchBestBets maps search queries to the URL paths (relative to Base, such as
+	// "/admin/install") of pages that are ranked first in their results, whether or not they match
+	// the query. Queries are matched regardless of case and whitespace.
+	SearchBestBets map[string]string
+
+	// SearchIndexPath is the path of a search index file (written by WriteSearchIndex) that is read
+	// instead of building the search index from the content. If it contains "$VERSION", that is
+	// replaced by the content version (empty for the default version); otherwise it is only used for
+	// the default version. Files that are stale or in an older format are ignored.
+	SearchIndexPath string
+
+	// SearchAnalytics, if set, records the searches served by the handler, and clicks on search
+	// results (whose links go through a URL that records the click and redirects to the result).
+	SearchAnalytics analytics.Sink
+
+	contentStampsMu sync.Mutex
+	contentStamps   map[string]*contentStampEntry // cached stamp of each content version (see contentStamp)
+
+	searchIndexesMu   sync.Mutex
+	searchIndexes     map[string]*searchIndexCacheEntry // cached search index for each content version
+	searchIndexBuilds map[string]*searchIndexBuild      // in-progress search index build for each content version
+
+	navTreesMu sync.Mutex
+	navTrees   map[string]*navTreeCacheEntry // cached navigation tree for each content version
+
+	sitemaps xmlCache // cached sitemap for each content version
+	feeds    xmlCache // cached feed for each content version, directory, and format
 }
 
 func (s *Site) GetResources(dir, version string) (http.FileSystem, error) {
@@ -89,11 +146,10 @@ func (s *Site) newContentPage(filePath string, data []byte, contentVersion strin
 		return nil, errors.WithMessage(err, fmt.Sprintf("run Markdown for %s", filePath))
 	}
 	return &ContentPage{
-		Path:        path,
-		FilePath:    filePath,
-		Data:        data,
-		Doc:         *doc,
-		Breadcrumbs: makeBreadcrumbEntries(path),
+		Path:     path,
+		FilePath: filePath,
+		Data:     data,
+		Doc:      *doc,
 	}, nil
 }
 
@@ -126,7 +182,35 @@ func (s *Site) AllContentPages(ctx context.Context, contentVersion string) ([]*C
 	if err != nil {
 		return nil, err
 	}
+	return s.allContentPages(ctx, content, contentVersion)
+}
+
+// allContentPages returns all content pages in the file system, in the order that WalkFileSystem
+// visits them. The pages are read and rendered concurrently.
+func (s *Site) allContentPages(ctx context.Context, content http.FileSystem, contentVersion string) ([]*ContentPage, error) {
+	paths, err := s.contentPagePaths(content)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]*ContentPage, len(paths))
+	err = forEachParallel(ctx, len(paths), func(ctx context.Context, i int) error {
+		data, err := ReadFile(content, paths[i])
+		if err != nil {
+			return err
+		}
+		pages[i], err = s.newContentPage(paths[i], data, contentVersion)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
 
+// contentPagePaths returns the file paths of all content pages in the file system, in the order
+// that WalkFileSystem visits them.
+func (s *Site) contentPagePaths(content http.FileSystem) ([]string, error) {
 	filter := func(path string) bool {
 		if !isContentPage(path) {
 			return false
@@ -134,20 +218,52 @@ func (s *Site) AllContentPages(ctx context.Context, contentVersion string) ([]*C
 		return s.checkIsValidPath(path) == nil
 	}
 
-	var pages []*ContentPage
-	err = WalkFileSystem(content, filter, func(path string) error {
-		data, err := ReadFile(content, path)
+	var paths []string
+	if err := WalkFileSystem(content, filter, func(path string) error {
+		paths = append(paths, path)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// contentPageHeaders returns the content pages at the file paths with only their metadata and
+// title (see markdown.ParseHeader), which is much faster than rendering them. Pages whose metadata
+// is invalid are logged and omitted, so that one broken page doesn't break the data (such as the
+// navigation tree) built from all pages.
+func (s *Site) contentPageHeaders(ctx context.Context, content http.FileSystem, paths []string) ([]*ContentPage, error) {
+	pages := make([]*ContentPage, len(paths))
+	err := forEachParallel(ctx, len(paths), func(ctx context.Context, i int) error {
+		data, err := ReadFile(content, paths[i])
 		if err != nil {
 			return err
 		}
-		page, err := s.newContentPage(path, data, contentVersion)
+		doc, err := markdown.ParseHeader(data)
 		if err != nil {
-			return err
+			log.Printf("# Skipping content page %s: %s", paths[i], err)
+			return nil
+		}
+		pages[i] = &ContentPage{
+			Path:     contentFilePathToPath(paths[i]),
+			FilePath: paths[i],
+			Data:     data,
+			Doc:      *doc,
 		}
-		pages = append(pages, page)
 		return nil
 	})
-	return pages, err
+	if err != nil {
+		return nil, err
+	}
+
+	n := 0
+	for _, page := range pages {
+		if page != nil {
+			pages[n] = page
+			n++
+		}
+	}
+	return pages[:n], nil
 }
 
 // ResolveContentPage looks up the content page at the given version and path (which generally comes
@@ -187,6 +303,15 @@ type PageData struct {
 
 	// Content is the content page, when it is found.
 	Content *ContentPage
+
+	// RobotsMeta is the content of the robots meta tag for the page (such as "noindex,nofollow"), or
+	// empty if search engines may index the page (see Site.IndexedVersions). Templates should render it
+	// as <meta name="robots" content="{{.RobotsMeta}}"> if it is non-empty.
+	RobotsMeta string
+
+	// NavTree is the navigation tree of the content version, with the content page marked as active
+	// (see Site.NavTree). It is nil if the version was not found.
+	NavTree *NavNode
 }
 
 // RenderContentPage renders a content page using the template.
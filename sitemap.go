@@ -0,0 +1,250 @@
+package docsite
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sitemapFileName      = "sitemap.xml"       // the sitemap of a content version (at its URL path)
+	sitemapIndexFileName = "sitemap_index.xml" // the sitemap index of the site (at the base URL path)
+
+	sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+var (
+	errSitemapNoRoot     = errors.New("sitemaps require the site's root URL")
+	errSitemapNotIndexed = errors.New("no sitemap for content versions that are not indexed")
+)
+
+// sitemapURLSet is a sitemap (see https://www.sitemaps.org/protocol.html).
+type sitemapURLSet struct {
+	XMLName xml.Name       `xml:"urlset"`
+	XMLNS   string         `xml:"xmlns,attr"`
+	URLs    []sitemapEntry `xml:"url"`
+}
+
+// sitemapIndex is a sitemap index, which lists other sitemaps.
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	XMLNS    string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// sitemapEntry is a page in a sitemap, or a sitemap in a sitemap index.
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Sitemap returns the sitemap (in the XML format of https://www.sitemaps.org) of the content
+// version's pages, with their absolute URLs (using Root) and last modification times (from their
+// "lastUpdated" metadata or their file modification time).
+//
+// Only the IndexedVersions have sitemaps (because the pages of other versions must not be indexed).
+// Pages that are excluded from search (by SkipIndexURLPattern) or have "noindex" or invalid metadata
+// are omitted. The sitemap is built once and reused until the version's file system is replaced or
+// its files change (see contentStamp).
+func (s *Site) Sitemap(ctx context.Context, contentVersion string) ([]byte, error) {
+	if s.Root == nil {
+		return nil, errSitemapNoRoot
+	}
+	if !s.isIndexedVersion(contentVersion) {
+		return nil, errSitemapNotIndexed
+	}
+	content, err := s.Content.OpenVersion(ctx, contentVersion)
+	if err != nil {
+		return nil, err
+	}
+	stamp, err := s.contentStamp(contentVersion, content)
+	if err != nil {
+		return nil, err
+	}
+	return s.sitemaps.get(contentVersion, content, stamp, func() ([]byte, error) {
+		return s.buildSitemap(ctx, content, contentVersion)
+	})
+}
+
+// buildSitemap builds the sitemap of the content pages in the file system. Only the pages'
+// metadata is read (the pages are not rendered).
+func (s *Site) buildSitemap(ctx context.Context, content http.FileSystem, contentVersion string) ([]byte, error) {
+	paths, err := s.contentPagePaths(content)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := s.contentPageHeaders(ctx, content, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	urlSet := sitemapURLSet{XMLNS: sitemapNamespace, URLs: []sitemapEntry{}}
+	for _, page := range pages {
+		if s.SkipIndexURLPattern != nil && s.SkipIndexURLPattern.MatchString(page.Path) {
+			continue
+		}
+		if page.Doc.Meta.NoIndex {
+			continue
+		}
+		lastMod, err := sitemapLastMod(content, page)
+		if err != nil {
+			return nil, err
+		}
+		urlSet.URLs = append(urlSet.URLs, sitemapEntry{
+			Loc:     s.absoluteURL(versionedPath(contentVersion, page.Path)),
+			LastMod: lastMod,
+		})
+	}
+	return marshalXML(urlSet)
+}
+
+// SitemapIndex returns the sitemap index that lists the sitemaps of the default content version and
+// of the SitemapVersions (of those that are IndexedVersions).
+func (s *Site) SitemapIndex() ([]byte, error) {
+	if s.Root == nil {
+		return nil, errSitemapNoRoot
+	}
+	versions := s.sitemapIndexVersions()
+	if len(versions) == 0 {
+		return nil, errSitemapNotIndexed
+	}
+	index := sitemapIndex{XMLNS: sitemapNamespace}
+	for _, contentVersion := range versions {
+		index.Sitemaps = append(index.Sitemaps, sitemapEntry{
+			Loc: s.absoluteURL(versionedPath(contentVersion, sitemapFileName)),
+		})
+	}
+	return marshalXML(index)
+}
+
+// sitemapIndexVersions returns the content versions whose sitemaps are listed in the sitemap index.
+func (s *Site) sitemapIndexVersions() []string {
+	var versions []string
+	for _, contentVersion := range append([]string{""}, s.SitemapVersions...) {
+		if s.isIndexedVersion(contentVersion) {
+			versions = append(versions, contentVersion)
+		}
+	}
+	return versions
+}
+
+// absoluteURL returns the absolute URL (using Root) of the path relative to the site base.
+func (s *Site) absoluteURL(path string) string {
+	base := s.Base
+	if base == nil {
+		base = &url.URL{Path: "/"}
+	}
+	return s.Root.ResolveReference(base.ResolveReference(&url.URL{Path: path})).String()
+}
+
+// sitemapLastMod returns the last modification time of the page for its sitemap entry, or empty
+// if it is unknown. An invalid "lastUpdated" is logged and omitted, so that one page doesn't break
+// the sitemap.
+func sitemapLastMod(content http.FileSystem, page *ContentPage) (string, error) {
+	if lastUpdated := page.Doc.Meta.LastUpdated; lastUpdated != "" {
+		if _, err := time.Parse(dateLayout, lastUpdated); err == nil {
+			return lastUpdated, nil
+		}
+		t, err := parseMetadataTime(page, "lastUpdated", lastUpdated)
+		if err != nil {
+			log.Printf("# Omitting sitemap lastmod: %s", err)
+			return "", nil
+		}
+		return t.UTC().Format(time.RFC3339), nil
+	}
+
+	modTime, err := fileModTime(content, page.FilePath)
+	if err != nil || modTime.IsZero() {
+		return "", err
+	}
+	return modTime.UTC().Format(time.RFC3339), nil
+}
+
+// dateLayout is the layout of a date (with no time) in page metadata.
+const dateLayout = "2006-01-02"
+
+// parseMetadataTime parses the value of the page's date or time metadata field, which must be a
+// date (YYYY-MM-DD) or an RFC 3339 time.
+func parseMetadataTime(page *ContentPage, field, value string) (time.Time, error) {
+	if t, err := time.Parse(dateLayout, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: invalid %s %q (must be YYYY-MM-DD or RFC 3339)", page.FilePath, field, value)
+	}
+	return t, nil
+}
+
+// fileModTime returns the modification time of the file, which is zero if it is unknown.
+func fileModTime(content http.FileSystem, path string) (time.Time, error) {
+	f, err := content.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fi.ModTime(), nil
+}
+
+// xmlCache caches XML documents (such as sitemaps) built from the content, each until the file
+// system that it was built from is replaced or its files change.
+type xmlCache struct {
+	mu      sync.Mutex
+	entries map[string]*xmlCacheEntry
+}
+
+// xmlCacheEntry is an XML document built from a version of the content.
+type xmlCacheEntry struct {
+	content http.FileSystem // the content file system that the document was built from
+	stamp   string          // the stamp of the content when the document was built
+	data    []byte
+}
+
+// get returns the cached document for the key if it was built from the content file system with
+// the stamp, and otherwise builds (and caches) it.
+func (c *xmlCache) get(key string, content http.FileSystem, stamp string, build func() ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	e := c.entries[key]
+	c.mu.Unlock()
+	if e != nil && sameFileSystem(e.content, content) && e.stamp == stamp {
+		return e.data, nil
+	}
+
+	data, err := build()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = map[string]*xmlCacheEntry{}
+	}
+	c.entries[key] = &xmlCacheEntry{content: content, stamp: stamp, data: data}
+	c.mu.Unlock()
+	return data, nil
+}
+
+// marshalXML returns the XML document (with an XML header) of v.
+func marshalXML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
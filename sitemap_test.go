@@ -0,0 +1,259 @@
+package docsite
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/godoc/vfs/httpfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func TestSite_Sitemap(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.md":     "# Home",
+		"a.md":         "---\nlastUpdated: 2023-04-05\n---\n# A",
+		"b/c.md":       "---\nlastUpdated: 2023-04-05T10:00:00+02:00\n---\n# C",
+		"hidden.md":    "---\nnoindex: true\n---\n# Hidden",
+		"skip/x.md":    "# Skipped",
+		"excluded.md":  "# Excluded",
+		"b/img/d.png":  "",
+		"_nav.yml":     "- a\n",
+		"b/index.html": "",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	modTime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dir, "index.md"), modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	site := Site{
+		Content: versionedFileSystem{
+			"":   http.Dir(dir),
+			"v1": httpfs.New(mapfs.New(map[string]string{"a.md": "a"})),
+		},
+		ContentExcludePattern: regexp.MustCompile(`excluded\.md$`),
+		SkipIndexURLPattern:   regexp.MustCompile(`^skip/`),
+		Base:                  &url.URL{Path: "/help/"},
+		Root:                  &url.URL{Scheme: "https", Host: "example.com"},
+		IndexedVersions:       []string{"", "v1"},
+		SitemapVersions:       []string{"v1"},
+	}
+	ctx := context.Background()
+
+	t.Run("default version", func(t *testing.T) {
+		data, err := site.Sitemap(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.com/help/a</loc>
+    <lastmod>2023-04-05</lastmod>
+  </url>
+  <url>
+    <loc>https://example.com/help/</loc>
+    <lastmod>2022-01-02T03:04:05Z</lastmod>
+  </url>
+  <url>
+    <loc>https://example.com/help/b/c</loc>
+    <lastmod>2023-04-05T08:00:00Z</lastmod>
+  </url>
+</urlset>
+`
+		if diff := cmp.Diff(want, string(data)); diff != "" {
+			t.Errorf("sitemap mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("other version", func(t *testing.T) {
+		data, err := site.Sitemap(ctx, "v1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "<url>\n    <loc>https://example.com/help/@v1/a</loc>\n  </url>"; !strings.Contains(string(data), want) {
+			t.Errorf("got sitemap %s, want it to contain %q", data, want)
+		}
+	})
+
+	t.Run("index", func(t *testing.T) {
+		data, err := site.SitemapIndex()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap>
+    <loc>https://example.com/help/sitemap.xml</loc>
+  </sitemap>
+  <sitemap>
+    <loc>https://example.com/help/@v1/sitemap.xml</loc>
+  </sitemap>
+</sitemapindex>
+`
+		if diff := cmp.Diff(want, string(data)); diff != "" {
+			t.Errorf("sitemap index mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("version not indexed", func(t *testing.T) {
+		site := Site{Content: site.Content, Root: site.Root, IndexedVersions: []string{"v1"}}
+		if _, err := site.Sitemap(ctx, ""); err != errSitemapNotIndexed {
+			t.Errorf("got error %v, want %v", err, errSitemapNotIndexed)
+		}
+	})
+
+	t.Run("index omits versions that are not indexed", func(t *testing.T) {
+		site := Site{Content: site.Content, Root: site.Root, IndexedVersions: []string{"v1"}, SitemapVersions: []string{"v1", "v2"}}
+		data, err := site.SitemapIndex()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(data); strings.Count(got, "<sitemap>") != 1 || !strings.Contains(got, "<loc>https://example.com/@v1/sitemap.xml</loc>") {
+			t.Errorf("got sitemap index %s, want only the sitemap of v1", got)
+		}
+
+		site.IndexedVersions = nil
+		if _, err := site.SitemapIndex(); err != errSitemapNotIndexed {
+			t.Errorf("got error %v, want %v", err, errSitemapNotIndexed)
+		}
+	})
+
+	t.Run("invalid lastUpdated", func(t *testing.T) {
+		site := Site{
+			Content:         versionedFileSystem{"": httpfs.New(mapfs.New(map[string]string{"a.md": "---\nlastUpdated: yesterday\n---\n"}))},
+			Root:            &url.URL{Scheme: "https", Host: "example.com"},
+			IndexedVersions: []string{""},
+		}
+		data, err := site.Sitemap(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(data), "<url>\n    <loc>https://example.com/a</loc>\n  </url>"; !strings.Contains(got, want) {
+			t.Errorf("got sitemap %s, want it to contain %q (with no lastmod)", got, want)
+		}
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		site := Site{
+			Content: versionedFileSystem{"": httpfs.New(mapfs.New(map[string]string{
+				"a.md": "# A",
+				"b.md": "---\ntitle: [\n---\n# B",
+			}))},
+			Root:            &url.URL{Scheme: "https", Host: "example.com"},
+			IndexedVersions: []string{""},
+		}
+		data, err := site.Sitemap(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(data); !strings.Contains(got, "https://example.com/a") || strings.Contains(got, "https://example.com/b") {
+			t.Errorf("got sitemap %s, want only a", got)
+		}
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		files := map[string]string{"a.md": "# A"}
+		content := versionedFileSystem{"": httpfs.New(mapfs.New(files))}
+		site := Site{Content: content, Root: &url.URL{Scheme: "https", Host: "example.com"}, IndexedVersions: []string{""}}
+		sitemap := func() string {
+			t.Helper()
+			data, err := site.Sitemap(ctx, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(data)
+		}
+
+		if got := sitemap(); !strings.Contains(got, "https://example.com/a") {
+			t.Fatalf("got sitemap %s, want it to contain a", got)
+		}
+		files["b.md"] = "# B" // the file system is not replaced, so the cached sitemap is reused
+		if got := sitemap(); strings.Contains(got, "https://example.com/b") {
+			t.Errorf("got sitemap %s, want the cached sitemap", got)
+		}
+		content[""] = httpfs.New(mapfs.New(files))
+		if got := sitemap(); !strings.Contains(got, "https://example.com/b") {
+			t.Errorf("got sitemap %s, want it rebuilt with b", got)
+		}
+	})
+
+	t.Run("no root URL", func(t *testing.T) {
+		site := Site{Content: site.Content}
+		if _, err := site.Sitemap(ctx, ""); err != errSitemapNoRoot {
+			t.Errorf("got error %v, want %v", err, errSitemapNoRoot)
+		}
+	})
+}
+
+func TestSite_Handler_sitemap(t *testing.T) {
+	site := Site{
+		Content: versionedFileSystem{
+			"":   httpfs.New(mapfs.New(map[string]string{"a.md": "a"})),
+			"v1": httpfs.New(mapfs.New(map[string]string{"b.md": "b"})),
+		},
+		Base:            &url.URL{Path: "/"},
+		Root:            &url.URL{Scheme: "https", Host: "example.com"},
+		IndexedVersions: []string{"", "v1", "v2"},
+		SitemapVersions: []string{"v1"},
+	}
+	handler := site.Handler()
+
+	tests := map[string]struct {
+		wantStatus int
+		wantBody   string
+	}{
+		"/sitemap.xml":       {wantStatus: http.StatusOK, wantBody: "<loc>https://example.com/a</loc>"},
+		"/@v1/sitemap.xml":   {wantStatus: http.StatusOK, wantBody: "<loc>https://example.com/@v1/b</loc>"},
+		"/sitemap_index.xml": {wantStatus: http.StatusOK, wantBody: "<loc>https://example.com/@v1/sitemap.xml</loc>"},
+		"/@v2/sitemap.xml":   {wantStatus: http.StatusInternalServerError},
+		"/@v3/sitemap.xml":   {wantStatus: http.StatusNotFound}, // not indexed
+	}
+	for path, test := range tests {
+		t.Run(path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", path, nil)
+			handler.ServeHTTP(rr, req)
+			if rr.Code != test.wantStatus {
+				t.Fatalf("got HTTP status %d, want %d", rr.Code, test.wantStatus)
+			}
+			if test.wantStatus != http.StatusOK {
+				return
+			}
+			if got, want := rr.Header().Get("Content-Type"), "application/xml; charset=utf-8"; got != want {
+				t.Errorf("got Content-Type %q, want %q", got, want)
+			}
+			if !strings.Contains(rr.Body.String(), test.wantBody) {
+				t.Errorf("got body %q, want it to contain %q", rr.Body.String(), test.wantBody)
+			}
+		})
+	}
+
+	t.Run("no root URL", func(t *testing.T) {
+		site := Site{Content: site.Content, Base: &url.URL{Path: "/"}}
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/sitemap.xml", nil)
+		site.Handler().ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("got HTTP status %d, want %d", rr.Code, http.StatusNotFound)
+		}
+	})
+}
@@ -84,6 +84,9 @@ func (s *Site) getTemplate(templatesFS http.FileSystem, name string, extraFuncs
 			}
 			return template.HTML(doc.HTML), nil
 		},
+		"navTree": func(version, path string) (*NavNode, error) {
+			return s.NavTree(context.Background(), version, path)
+		},
 		"subtract":   func(a, b int) int { return a - b },
 		"replace":    strings.Replace,
 		"trimPrefix": strings.TrimPrefix,
@@ -104,7 +107,9 @@ func (s *Site) getTemplate(templatesFS http.FileSystem, name string, extraFuncs
 
 	// Read root and named template files.
 	names := []string{rootTemplateName, name}
-	for _, name := range names {
+	datas := make([][]byte, len(names))
+	rendersRobotsMeta := false
+	for i, name := range names {
 		path := "/" + name + ".html"
 		data, err := ReadFile(templatesFS, path)
 		if name == rootTemplateName && os.IsNotExist(err) {
@@ -113,13 +118,23 @@ func (s *Site) getTemplate(templatesFS http.FileSystem, name string, extraFuncs
 		if err != nil {
 			return nil, errors.WithMessage(err, fmt.Sprintf("read template %s", path))
 		}
-		if name == documentTemplateName {
+		datas[i] = data
+		if bytes.Contains(data, []byte("RobotsMeta")) {
+			rendersRobotsMeta = true
+		}
+	}
+	for i, name := range names {
+		data := datas[i]
+		if data == nil {
+			continue
+		}
+		if name == documentTemplateName && !rendersRobotsMeta {
 			// We need to patch the template, since if we're loading an old version the template won't have the
-			// nofollow, noindex seo tag
+			// nofollow, noindex seo tag. Templates that render PageData.RobotsMeta are not patched.
 			data = patchTemplateForSEO(data)
 		}
 		if _, err := tmpl.Parse(string(data)); err != nil {
-			return nil, errors.WithMessage(err, fmt.Sprintf("parse template %s", path))
+			return nil, errors.WithMessage(err, fmt.Sprintf("parse template /%s.html", name))
 		}
 	}
 	return tmpl, nil
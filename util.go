@@ -1,8 +1,11 @@
 package docsite
 
 import (
+	"context"
 	"io"
 	"net/http"
+	"runtime"
+	"sync"
 )
 
 func ReadFile(fs http.FileSystem, path string) ([]byte, error) {
@@ -13,3 +16,52 @@ func ReadFile(fs http.FileSystem, path string) ([]byte, error) {
 	defer f.Close()
 	return io.ReadAll(f)
 }
+
+// forEachParallel calls f for each i in [0, n), with up to runtime.GOMAXPROCS(0) calls running
+// concurrently. After f returns an error or ctx is done, no more calls are started, and (once the
+// running calls return) the first error or ctx.Err() is returned.
+func forEachParallel(ctx context.Context, n int, f func(ctx context.Context, i int) error) error {
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	indexes := make(chan int)
+	for w := 0; w < min(runtime.GOMAXPROCS(0), n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if workCtx.Err() != nil {
+					continue
+				}
+				if err := f(workCtx, i); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					cancel()
+				}
+			}
+		}()
+	}
+send:
+	for i := 0; i < n; i++ {
+		select {
+		case indexes <- i:
+		case <-workCtx.Done():
+			break send
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
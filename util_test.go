@@ -0,0 +1,61 @@
+package docsite
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestForEachParallel(t *testing.T) {
+	t.Run("all", func(t *testing.T) {
+		got := make([]int, 100)
+		if err := forEachParallel(context.Background(), len(got), func(_ context.Context, i int) error {
+			got[i] = i * i
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range got {
+			if v != i*i {
+				t.Fatalf("got %d at %d, want %d", v, i, i*i)
+			}
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		wantErr := errors.New("x")
+		var calls int32
+		err := forEachParallel(context.Background(), 1000, func(ctx context.Context, i int) error {
+			atomic.AddInt32(&calls, 1)
+			if i == 0 {
+				return wantErr
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		if err != wantErr {
+			t.Errorf("got error %v, want %v", err, wantErr)
+		}
+		if calls == 1000 {
+			t.Error("got calls for all indexes after error")
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var calls int32
+		err := forEachParallel(ctx, 10, func(context.Context, int) error {
+			atomic.AddInt32(&calls, 1)
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, want %v", err, context.Canceled)
+		}
+		if calls != 0 {
+			t.Errorf("got %d calls, want 0", calls)
+		}
+	})
+}